@@ -0,0 +1,234 @@
+// Copyright (c) 2024 go-reloaded contributors
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+func runBatch(args []string) int {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	opts := addFlags(flags)
+	workers := flags.Int("workers", runtime.GOMAXPROCS(0), "format `N` files at a time")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s batch [flags] <path>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 1
+	}
+	if *workers < 1 {
+		fmt.Fprintf(os.Stderr, "Error: invalid number of workers %d\n", *workers)
+		return 1
+	}
+	if opts.Report != "" {
+		fmt.Fprintln(os.Stderr, "Error: --report is not supported by batch")
+		return 1
+	}
+
+	opts.set = flagsSet(flags)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	sum := formatBatch(ctx, os.Stdout, flags.Args(), *opts, *workers)
+	if sum.Failed > 0 || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// batchFile is a file for formatBatch, or the error that stopped it from
+// finding files under a path.
+type batchFile struct {
+	path string
+	err  error
+}
+
+// batchResult is what formatBatch did with a file.
+type batchResult struct {
+	path    string
+	changed bool
+	err     error
+}
+
+// batchSummary counts what formatBatch did.
+type batchSummary struct {
+	Scanned, Changed, Failed int
+	Elapsed                  time.Duration
+}
+
+// formatBatch formats files in place, up to workers at a time, and writes
+// a line per file to out, in the order of paths, followed by a summary. A
+// directory stands for the .txt and .md files under it, as in watch mode.
+// A file that fails is reported and does not stop the others; cancelling
+// ctx does, and the files not yet started are left out of the summary.
+func formatBatch(ctx context.Context, out io.Writer, paths []string, opts options, workers int) batchSummary {
+	start := time.Now()
+	files := batchFiles(paths)
+	results := make([]batchResult, len(files))
+
+	next := make(chan int)
+	done := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range files {
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range min(workers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				f := files[i]
+				results[i] = batchResult{path: f.path, err: f.err}
+				if f.err == nil {
+					results[i].changed, results[i].err = formatInPlace(ctx, f.path, opts)
+				}
+				done <- i
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var sum batchSummary
+	report := func(r batchResult) {
+		sum.Scanned++
+		switch {
+		case r.err != nil:
+			sum.Failed++
+			fmt.Fprintf(out, "failed    %s: %v\n", r.path, r.err)
+		case r.changed:
+			sum.Changed++
+			fmt.Fprintf(out, "changed   %s\n", r.path)
+		default:
+			fmt.Fprintf(out, "unchanged %s\n", r.path)
+		}
+	}
+
+	// Report results in order as soon as those before them are in
+	finished := make([]bool, len(files))
+	reported := 0
+	for i := range done {
+		finished[i] = true
+		for reported < len(files) && finished[reported] {
+			report(results[reported])
+			reported++
+		}
+	}
+	// After a cancellation, files that were never started leave gaps
+	for ; reported < len(files); reported++ {
+		if finished[reported] {
+			report(results[reported])
+		}
+	}
+
+	sum.Elapsed = time.Since(start)
+	if ctx.Err() != nil {
+		fmt.Fprintf(out, "interrupted after %d of %d file(s)\n", sum.Scanned, len(files))
+	}
+	fmt.Fprintf(out, "%d file(s) scanned, %d changed, %d failed in %s\n",
+		sum.Scanned, sum.Changed, sum.Failed, sum.Elapsed.Round(time.Millisecond))
+	return sum
+}
+
+// batchFiles expands paths into the files to format. Directories are
+// walked for .txt and .md files, skipping hidden directories; other paths
+// are taken as they are. A file named twice is only formatted once.
+func batchFiles(paths []string) []batchFile {
+	var files []batchFile
+	seen := make(map[string]bool)
+	add := func(path string) {
+		key, err := filepath.Abs(path)
+		if err != nil {
+			key = filepath.Clean(path)
+		}
+		if !seen[key] {
+			seen[key] = true
+			files = append(files, batchFile{path: path})
+		}
+	}
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			files = append(files, batchFile{path: root, err: err})
+			continue
+		}
+		if !info.IsDir() {
+			add(root)
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				files = append(files, batchFile{path: path, err: err})
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if watchExtensions[strings.ToLower(filepath.Ext(path))] {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			files = append(files, batchFile{path: root, err: err})
+		}
+	}
+	return files
+}
+
+// formatInPlace formats the file at path and rewrites it if that changed
+// anything, which it reports.
+func formatInPlace(ctx context.Context, path string, opts options) (bool, error) {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	result, _, err := formatFile(ctx, path, input, opts)
+	if err != nil {
+		return false, err
+	}
+	if result == string(input) {
+		return false, nil
+	}
+
+	if err := writeFile(path, []byte(result), opts.Backup); err != nil {
+		return false, fmt.Errorf("writing output: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatBatch(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, filepath.Join(dir, "b.txt"), "Already fine.", now)
+	touch(t, filepath.Join(dir, "main.go"), "package main // a apple", now)
+	touch(t, filepath.Join(dir, ".git", "d.txt"), "a apple", now)
+	extra := filepath.Join(t.TempDir(), "extra.go")
+	missing := filepath.Join(dir, "missing.txt")
+
+	for _, workers := range []int{1, 3} {
+		// Files named directly are formatted whatever their extension;
+		// a.txt is named twice but formatted once
+		touch(t, filepath.Join(dir, "a.txt"), "hello (up) world", now)
+		touch(t, filepath.Join(dir, "sub", "c.md"), "it was a honor", now)
+		touch(t, extra, "// a apple\npackage main\n", now)
+
+		var out bytes.Buffer
+		paths := []string{dir, missing, extra, filepath.Join(dir, "a.txt")}
+		sum := formatBatch(context.Background(), &out, paths, options{}, workers)
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		want := []string{
+			"changed   " + filepath.Join(dir, "a.txt"),
+			"unchanged " + filepath.Join(dir, "b.txt"),
+			"changed   " + filepath.Join(dir, "sub", "c.md"),
+			"failed    " + missing,
+			"changed   " + extra,
+		}
+		if len(lines) != len(want)+1 {
+			t.Fatalf("workers=%d: output =\n%s", workers, out.String())
+		}
+		for i, w := range want {
+			if !strings.HasPrefix(lines[i], w) {
+				t.Errorf("workers=%d: line %d = %q, want prefix %q", workers, i+1, lines[i], w)
+			}
+		}
+		if summary := lines[len(lines)-1]; !strings.HasPrefix(summary, "5 file(s) scanned, 3 changed, 1 failed in ") {
+			t.Errorf("workers=%d: summary = %q", workers, summary)
+		}
+		if got := (batchSummary{sum.Scanned, sum.Changed, sum.Failed, 0}); !reflect.DeepEqual(got, batchSummary{5, 3, 1, 0}) {
+			t.Errorf("workers=%d: summary = %+v", workers, sum)
+		}
+
+		if got, want := readFile(t, filepath.Join(dir, "a.txt")), "HELLO world"; got != want {
+			t.Errorf("a.txt = %q, want %q", got, want)
+		}
+		if got, want := readFile(t, filepath.Join(dir, "sub", "c.md")), "it was an honor"; got != want {
+			t.Errorf("c.md = %q, want %q", got, want)
+		}
+		if got, want := readFile(t, extra), "// an apple\npackage main\n"; got != want {
+			t.Errorf("extra.go = %q, want %q", got, want)
+		}
+		if got, want := readFile(t, filepath.Join(dir, ".git", "d.txt")), "a apple"; got != want {
+			t.Errorf("file in hidden directory changed: %q", got)
+		}
+	}
+}
+
+func TestFormatBatch_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		touch(t, filepath.Join(dir, name), "a apple", time.Now())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	sum := formatBatch(ctx, &out, []string{dir}, options{}, 1)
+	if sum.Changed != 0 {
+		t.Errorf("changed %d file(s) after cancellation", sum.Changed)
+	}
+	if !strings.Contains(out.String(), "interrupted") {
+		t.Errorf("output does not mention the interruption:\n%s", out.String())
+	}
+	if got := readFile(t, filepath.Join(dir, "a.txt")); got != "a apple" {
+		t.Errorf("a.txt = %q after cancellation", got)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2024 go-reloaded contributors
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/pkg/plugin"
+)
+
+func runConfig(args []string) int {
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	opts := addFlags(flags)
+	printEffective := flags.Bool("print", false, "print the effective configuration")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s config --print [flags] [path]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if !*printEffective || flags.NArg() > 1 {
+		flags.Usage()
+		return 1
+	}
+	path := "."
+	if flags.NArg() == 1 {
+		path = flags.Arg(0)
+	}
+
+	opts.set = flagsSet(flags)
+	if err := printConfig(os.Stdout, os.Stderr, path, *opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printConfig writes the configuration that applies to path, a file or a
+// directory, with flags and defaults filled in, as JSON to out. It names
+// the file it came from on info.
+func printConfig(out, info io.Writer, path string, opts options) error {
+	cfg, found, err := config.For(path)
+	if err != nil {
+		return fmt.Errorf("reading configuration: %w", err)
+	}
+	if found == "" {
+		fmt.Fprintf(info, "no %s found; using defaults\n", config.FileName)
+	} else {
+		fmt.Fprintf(info, "using %s\n", found)
+	}
+
+	popts := opts.Options
+	if err := applyConfig(cfg, &popts, opts.set); err != nil {
+		return err
+	}
+	if _, err := pipeline.NewWithOptions(popts); err != nil {
+		return fmt.Errorf("configuring pipeline: %w", err)
+	}
+	lineEndings := opts.LineEndings
+	if cfg.LineEndings != "" && !opts.set["line-endings"] {
+		lineEndings = cfg.LineEndings
+	}
+
+	effective := config.Config{
+		Stages:            popts.Stages,
+		Plugins:           popts.Plugins,
+		Locale:            popts.Locale,
+		DashStyle:         string(popts.DashStyle),
+		SentenceCase:      popts.SentenceCase,
+		ArticleExceptions: popts.ArticleExceptions,
+		Protected:         popts.Protected,
+		Markers:           cfg.Markers,
+		Commands:          cfg.Commands,
+		LineEndings:       lineEndings,
+		Lint:              cfg.Lint,
+	}
+	if len(effective.Stages) == 0 {
+		names := plugin.Builtin()
+		effective.Stages = slices.DeleteFunc(names, func(name string) bool {
+			return name == "sentence" || name == "macro" && len(popts.Macros) == 0
+		})
+	}
+	if effective.SentenceCase && !slices.Contains(effective.Stages, "sentence") {
+		effective.Stages = append(slices.Clone(effective.Stages), "sentence")
+	}
+	if effective.Locale == "" {
+		effective.Locale = "en"
+	}
+	if effective.LineEndings == "" {
+		effective.LineEndings = config.LineEndingsKeep
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(effective)
+}
+
+// configure applies the configuration file that governs path to opts and
+// returns it, for the settings opts does not hold. The file is found by
+// looking up from path, so it may come with a tree that is not trusted;
+// unless allowCommands is set, it fails if the file defines command
+// stages rather than run programs the file chose.
+func configure(path string, opts *pipeline.Options, set map[string]bool, allowCommands bool) (config.Config, error) {
+	cfg, found, err := config.For(path)
+	if err != nil {
+		return config.Config{}, fmt.Errorf("reading configuration: %w", err)
+	}
+	if len(cfg.Commands) > 0 && !allowCommands {
+		names := slices.Sorted(maps.Keys(cfg.Commands))
+		return config.Config{}, fmt.Errorf("%s defines command stages (%s), which only run with --allow-commands", found, strings.Join(names, ", "))
+	}
+	if err := applyConfig(cfg, opts, set); err != nil {
+		return config.Config{}, err
+	}
+	return cfg, nil
+}
+
+// applyConfig copies the settings cfg holds into opts, except those whose
+// flags were given on the command line, as recorded in set.
+func applyConfig(cfg config.Config, opts *pipeline.Options, set map[string]bool) error {
+	c, err := cfg.Pipeline()
+	if err != nil {
+		return fmt.Errorf("reading configuration: %w", err)
+	}
+	if c.Locale != "" && !set["locale"] {
+		opts.Locale = c.Locale
+	}
+	if c.DashStyle != "" && !set["dash-style"] {
+		opts.DashStyle = c.DashStyle
+	}
+	if c.SentenceCase && !set["sentence-case"] {
+		opts.SentenceCase = true
+	}
+	if len(c.Stages) > 0 {
+		opts.Stages = c.Stages
+	}
+	opts.ArticleExceptions = append(opts.ArticleExceptions, c.ArticleExceptions...)
+	opts.Protected = append(opts.Protected, c.Protected...)
+	opts.Plugins = append(opts.Plugins, c.Plugins...)
+	if len(c.Macros) > 0 {
+		opts.Macros = c.Macros
+	}
+	if len(c.Commands) > 0 {
+		opts.Commands = c.Commands
+	}
+	return nil
+}
+
+// flagsSet returns the names of the flags given on the command line.
+func flagsSet(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
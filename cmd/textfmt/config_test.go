@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/pipeline"
+)
+
+func TestRun_ConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := `{"locale": "fr", "sentenceCase": true, "lineEndings": "crlf", "articleExceptions": ["one"]}`
+	if err := os.WriteFile(filepath.Join(dir, config.FileName), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inPath := filepath.Join(dir, "in.txt")
+	outPath := filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(inPath, []byte("quoi ?a one day\nok"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts options
+		want string
+	}{
+		{
+			name: "file settings",
+			want: "Quoi\u202F? A one day\r\nok",
+		},
+		{
+			name: "flags win",
+			opts: options{
+				Options:     pipeline.Options{Locale: "en"},
+				LineEndings: config.LineEndingsKeep,
+				set:         map[string]bool{"locale": true, "line-endings": true},
+			},
+			want: "Quoi? A one day\nok",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runWithOptions(context.Background(), inPath, outPath, tt.opts); err != nil {
+				t.Fatalf("runWithOptions() error = %v", err)
+			}
+			out, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatalf("reading out: %v", err)
+			}
+			if got := string(out); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, config.FileName), []byte(`{"locale": 1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runWithOptions(context.Background(), inPath, outPath, options{}); err == nil || !strings.Contains(err.Error(), config.FileName) {
+		t.Errorf("runWithOptions() error = %v, want one naming the configuration file", err)
+	}
+
+	// A failing command stage fails the run instead of being skipped
+	if _, err := exec.LookPath("sh"); err == nil {
+		cfg := `{"commands": {"brands": {"run": ["sh", "-c", "echo no brands here >&2; exit 2"]}}}`
+		if err := os.WriteFile(filepath.Join(dir, config.FileName), []byte(cfg), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := runWithOptions(context.Background(), inPath, outPath, options{AllowCommands: true})
+		if err == nil || !strings.Contains(err.Error(), "stage brands") || !strings.Contains(err.Error(), "no brands here") {
+			t.Errorf("runWithOptions() error = %v, want the command's failure", err)
+		}
+	}
+}
+
+func TestRun_ConfigCommandsNeedOptIn(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	cfg := `{"commands": {"upper": {"run": ["sh", "-c", "touch ran; tr a-z A-Z"]}}}`
+	if err := os.WriteFile(filepath.Join(dir, config.FileName), []byte(cfg), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The configuration is found by looking up from the input file
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	inPath := filepath.Join(dir, "sub", "in.txt")
+	outPath := filepath.Join(dir, "sub", "out.txt")
+	if err := os.WriteFile(inPath, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runWithOptions(context.Background(), inPath, outPath, options{})
+	if err == nil || !strings.Contains(err.Error(), "--allow-commands") || !strings.Contains(err.Error(), "upper") {
+		t.Errorf("runWithOptions() error = %v, want one asking for --allow-commands", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("command ran without --allow-commands")
+	}
+	if _, err := os.Stat(outPath); err == nil {
+		t.Errorf("output written without --allow-commands")
+	}
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		return
+	}
+	if err := runWithOptions(context.Background(), inPath, outPath, options{AllowCommands: true}); err != nil {
+		t.Fatalf("runWithOptions() with --allow-commands error = %v", err)
+	}
+	if got := readFile(t, outPath); got != "HELLO" {
+		t.Errorf("got %q, want %q", got, "HELLO")
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, config.FileName), []byte(`{"dashStyle": "spaced", "lint": {"disable": ["quote"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, info bytes.Buffer
+	opts := options{Options: pipeline.Options{Locale: "fr"}, set: map[string]bool{"locale": true}}
+	if err := printConfig(&out, &info, dir, opts); err != nil {
+		t.Fatalf("printConfig() error = %v", err)
+	}
+	want := `{
+  "stages": [
+    "hexbin",
+    "case",
+    "article",
+    "quote",
+    "dash",
+    "punctuation"
+  ],
+  "locale": "fr",
+  "dashStyle": "spaced",
+  "lineEndings": "keep",
+  "lint": {
+    "disable": [
+      "quote"
+    ]
+  }
+}
+`
+	if got := out.String(); got != want {
+		t.Errorf("printConfig():\n%s\nwant:\n%s", got, want)
+	}
+	if !strings.Contains(info.String(), filepath.Join(dir, config.FileName)) {
+		t.Errorf("info = %q, want the configuration file's path", info.String())
+	}
+}
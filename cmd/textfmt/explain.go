@@ -0,0 +1,77 @@
+// Copyright (c) 2024 go-reloaded contributors
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go-reloaded/internal/explain"
+	"go-reloaded/internal/pipeline"
+)
+
+func runExplain(args []string) int {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	var opts pipeline.Options
+	var allowCommands bool
+	addPipelineFlags(flags, &opts, &allowCommands)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s explain [flags] <file>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	if _, err := configure(flags.Arg(0), &opts, flagsSet(flags), allowCommands); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := explainFile(os.Stdout, flags.Arg(0), opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// explainFile runs the pipeline over a file and writes one line per change
+// each stage made.
+func explainFile(out io.Writer, path string, opts pipeline.Options) error {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading input: %w", err)
+	}
+
+	var rec explain.Recorder
+	opts.Trace = rec.Trace
+	pl, err := pipeline.NewWithOptions(opts)
+	if err != nil {
+		return fmt.Errorf("configuring pipeline: %w", err)
+	}
+	if _, err := pl.Format(context.Background(), string(input)); err != nil {
+		return err
+	}
+
+	for _, c := range rec.Changes {
+		fmt.Fprintln(out, c)
+	}
+	if len(rec.Changes) == 0 {
+		fmt.Fprintln(out, "no changes")
+	} else {
+		fmt.Fprintf(out, "%d change(s)\n", len(rec.Changes))
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+)
+
+func TestExplainFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(path, []byte("it was a apple (up)"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := explainFile(&out, path, pipeline.Options{}); err != nil {
+		t.Fatalf("explainFile() error = %v", err)
+	}
+	want := "CaseProcessor: (up) at 1:10 uppercased 'apple'\n" +
+		"ArticleProcessor: at 1:8 replaced 'a' with 'an'\n" +
+		"2 change(s)\n"
+	if got := out.String(); got != want {
+		t.Errorf("explainFile():\n got  %q\n want %q", got, want)
+	}
+
+	out.Reset()
+	if err := explainFile(&out, filepath.Join(t.TempDir(), "missing.txt"), pipeline.Options{}); err == nil {
+		t.Error("expected error for a missing file")
+	}
+	if err := os.WriteFile(path, []byte("fine."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := explainFile(&out, path, pipeline.Options{}); err != nil || !strings.Contains(out.String(), "no changes") {
+		t.Errorf("explainFile() = %q, %v; want no changes", out.String(), err)
+	}
+}
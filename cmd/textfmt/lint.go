@@ -0,0 +1,150 @@
+// Copyright (c) 2024 go-reloaded contributors
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"go-reloaded/internal/formats"
+	"go-reloaded/internal/lint"
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/internal/report"
+)
+
+// errProblems is returned by lintFiles when it found something to report.
+var errProblems = errors.New("problems found")
+
+// lintOptions configures lintFiles.
+type lintOptions struct {
+	pipeline.Options
+	Lint lint.Options
+	// Format names the document handler; empty means detect it from each
+	// file's extension
+	Format        string
+	FormatOptions formats.Options
+	// Report names the format of the report to write instead of one line
+	// per problem; empty means lines
+	Report string
+	// AllowCommands lets the configuration file's command stages run
+	AllowCommands bool
+	// set holds the names of the flags given on the command line, which
+	// win over the configuration file
+	set map[string]bool
+}
+
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	var opts lintOptions
+	addPipelineFlags(flags, &opts.Options, &opts.AllowCommands)
+	addFormatFlags(flags, &opts.Format, &opts.FormatOptions)
+	flags.Func("disable", "comma-separated `rules` not to check", func(v string) error {
+		opts.Lint.Disable = append(opts.Lint.Disable, splitList(v)...)
+		return nil
+	})
+	flags.Func("enable", "comma-separated `rules` to check even if disabled", func(v string) error {
+		opts.Lint.Enable = append(opts.Lint.Enable, splitList(v)...)
+		return nil
+	})
+	flags.StringVar(&opts.Report, "report", "", "write a `format` report instead of one line per problem: "+strings.Join(report.Formats(), ", "))
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s lint [flags] <file>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 1
+	}
+
+	opts.set = flagsSet(flags)
+	err := lintFiles(os.Stdout, flags.Args(), opts)
+	if err != nil && !errors.Is(err, errProblems) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// lintFiles checks each file, with the configuration that governs it, and
+// writes what it found to out, either as "path:line:col: message [rule]"
+// lines or as a report. It returns errProblems if any file has a problem.
+func lintFiles(out io.Writer, paths []string, opts lintOptions) error {
+	if opts.Report != "" && !slices.Contains(report.Formats(), opts.Report) {
+		return fmt.Errorf("unknown report format %q (want one of: %s)", opts.Report, strings.Join(report.Formats(), ", "))
+	}
+
+	var r report.Report
+	problems := 0
+	for _, path := range paths {
+		popts := opts.Options
+		cfg, err := configure(path, &popts, opts.set, opts.AllowCommands)
+		if err != nil {
+			return err
+		}
+		pl, err := pipeline.NewWithOptions(popts)
+		if err != nil {
+			return fmt.Errorf("configuring pipeline: %w", err)
+		}
+		linter, err := lint.New(pl, lint.Options(cfg.Lint), opts.Lint)
+		if err != nil {
+			return err
+		}
+		handler, err := lookupFormat(path, opts.Format, opts.FormatOptions)
+		if err != nil {
+			return err
+		}
+
+		input, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading input: %w", err)
+		}
+		diags, err := linter.Lint(string(input), handler)
+		if err != nil {
+			return fmt.Errorf("linting %s: %w", path, err)
+		}
+		problems += len(diags)
+		r.Files = append(r.Files, report.NewFile(path, "", nil, diags))
+	}
+
+	if opts.Report != "" {
+		if err := report.Write(out, opts.Report, r); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+	} else {
+		for _, f := range r.Files {
+			for _, d := range f.Diagnostics {
+				fmt.Fprintf(out, "%s:%d:%d: %s [%s]\n", f.Path, d.Position.Line, d.Position.Column, d.Message, d.Rule)
+			}
+		}
+	}
+	if problems > 0 {
+		return errProblems
+	}
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-reloaded/internal/lint"
+)
+
+func TestLintFiles(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.txt")
+	good := filepath.Join(dir, "good.txt")
+	if err := os.WriteFile(bad, []byte("a apple\nwait ,what"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	if err := os.WriteFile(good, []byte("An apple."), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	var out bytes.Buffer
+	err := lintFiles(&out, []string{good, bad}, lintOptions{})
+	if !errors.Is(err, errProblems) {
+		t.Fatalf("lintFiles() error = %v, want errProblems", err)
+	}
+	want := bad + `:1:1: "a" should be "an" [article-mismatch]` + "\n" +
+		bad + ":2:5: extra space [punctuation-spacing]\n" +
+		bad + ":2:7: missing space [punctuation-spacing]\n"
+	if got := out.String(); got != want {
+		t.Errorf("lintFiles():\n got  %q\n want %q", got, want)
+	}
+
+	out.Reset()
+	if err := lintFiles(&out, []string{good}, lintOptions{Report: "sarif"}); err != nil {
+		t.Errorf("lintFiles() error = %v for a clean file", err)
+	}
+	if !strings.Contains(out.String(), `"version": "2.1.0"`) {
+		t.Errorf("expected a SARIF log, got %q", out.String())
+	}
+
+	// Only prose is checked, at its position in the file
+	code := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(code, []byte("package main\n\nfunc main() {\n\tfmt.Println(a(1 , 2)) // wait ,what\n}\n"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	out.Reset()
+	if err := lintFiles(&out, []string{code}, lintOptions{}); !errors.Is(err, errProblems) {
+		t.Fatalf("lintFiles() error = %v, want errProblems", err)
+	}
+	want = code + ":4:31: extra space [punctuation-spacing]\n" +
+		code + ":4:33: missing space [punctuation-spacing]\n"
+	if got := out.String(); got != want {
+		t.Errorf("lintFiles():\n got  %q\n want %q", got, want)
+	}
+	out.Reset()
+	if err := lintFiles(&out, []string{code}, lintOptions{Format: "text"}); !errors.Is(err, errProblems) || !strings.Contains(out.String(), ":4:17: extra space") {
+		t.Errorf("lintFiles() with --format text = %v:\n%s", err, out.String())
+	}
+
+	opts := lintOptions{Lint: lint.Options{Disable: []string{"article", "punctuation"}}}
+	if err := lintFiles(&out, []string{bad}, opts); err != nil {
+		t.Errorf("lintFiles() error = %v with the failing rules disabled", err)
+	}
+	opts = lintOptions{Lint: lint.Options{Disable: []string{"grammar"}}}
+	if err := lintFiles(&out, []string{bad}, opts); err == nil || errors.Is(err, errProblems) {
+		t.Errorf("lintFiles() error = %v, want an unknown rule error", err)
+	}
+}
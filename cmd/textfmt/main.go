@@ -4,59 +4,324 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 
+	"go-reloaded/internal/config"
+	"go-reloaded/internal/explain"
+	"go-reloaded/internal/formats"
 	"go-reloaded/internal/logger"
 	"go-reloaded/internal/pipeline"
+	"go-reloaded/internal/report"
+	"go-reloaded/pkg/processors"
 	"go-reloaded/pkg/tokenizer"
 )
 
+// subcommands run instead of the plain <input-file> <output-file> form
+// when named as the first argument. Each returns the exit code.
+var subcommands = map[string]func(args []string) int{
+	"watch":   runWatch,
+	"batch":   runBatch,
+	"repl":    runREPL,
+	"explain": runExplain,
+	"lint":    runLint,
+	"config":  runConfig,
+}
+
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <input-file> <output-file>\n", os.Args[0])
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	opts := addFlags(fs)
+	fs.BoolVar(&opts.InPlace, "w", false, "allow the output file to be the input file, which may then be given alone")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <input-file> <output-file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -w [flags] <file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s watch [flags] <dir>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s batch [flags] <path>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s repl [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s explain [flags] <file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s lint [flags] <file>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s config --print [flags] [path]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	}
+	if fs.NArg() != 2 && !(opts.InPlace && fs.NArg() == 1) {
+		fs.Usage()
 		os.Exit(1)
 	}
 
-	inPath := os.Args[1]
-	outPath := os.Args[2]
+	inPath := fs.Arg(0)
+	outPath := fs.Arg(fs.NArg() - 1)
+	opts.set = flagsSet(fs)
 
-	if err := run(inPath, outPath); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := runWithOptions(ctx, inPath, outPath, *opts); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// addFlags registers the formatting flags on fs and returns the options
+// they fill in once fs is parsed.
+func addFlags(fs *flag.FlagSet) *options {
+	opts := &options{}
+	addPipelineFlags(fs, &opts.Options, &opts.AllowCommands)
+	addFormatFlags(fs, &opts.Format, &opts.FormatOptions)
+	fs.Func("line-endings", "convert the output's line endings: keep, lf or crlf (default keep)", func(v string) error {
+		if !slices.Contains([]string{config.LineEndingsKeep, config.LineEndingsLF, config.LineEndingsCRLF}, v) {
+			return fmt.Errorf("want keep, lf or crlf")
+		}
+		opts.LineEndings = v
+		return nil
+	})
+	fs.IntVar(&opts.Jobs, "jobs", 0, "format up to `N` paragraphs at a time (default: one after the other)")
+	fs.StringVar(&opts.Backup, "backup", "", "keep the old content of each file overwritten under its name plus `suffix`, e.g. .orig")
+	fs.StringVar(&opts.Report, "report", "", "write a `format` report of the changes and diagnostics to standard output: "+strings.Join(report.Formats(), ", "))
+	return opts
+}
+
+// addPipelineFlags registers the flags that configure the pipeline stages.
+// --allow-commands sets allowCommands.
+func addPipelineFlags(fs *flag.FlagSet, opts *pipeline.Options, allowCommands *bool) {
+	fs.StringVar(&opts.Locale, "locale", "", "punctuation spacing rules: en, fr, fr-CH, zh, ja")
+	fs.Func("dash-style", "`style` of spacing around em dashes: spaced or unspaced (default: as typed)", func(v string) error {
+		opts.DashStyle = processors.DashStyle(v)
+		return nil
+	})
+	fs.BoolVar(&opts.SentenceCase, "sentence-case", false, "capitalize the first word of every sentence")
+	fs.Func("plugins", "comma-separated `names` of registered plugin stages to run", func(v string) error {
+		opts.Plugins = append(opts.Plugins, splitList(v)...)
+		return nil
+	})
+	fs.BoolVar(allowCommands, "allow-commands", false, "run the command stages the configuration file defines")
+}
+
+// addFormatFlags registers the flags that choose and configure the
+// document handler.
+func addFormatFlags(fs *flag.FlagSet, format *string, opts *formats.Options) {
+	fs.StringVar(format, "format", "", "input format: "+strings.Join(formats.Names(), ", ")+" (default: by file extension)")
+	fs.BoolVar(&opts.GoStrings, "go-strings", false, "with --format go, also format strings passed to fmt.Errorf, errors.New and log calls")
+	fs.Func("columns", "with --format csv, comma-separated `list` of columns to format, numbered from 1 (default: all)", func(v string) error {
+		cols, err := parseColumns(v)
+		opts.Columns = cols
+		return err
+	})
+	fs.BoolVar(&opts.NoHeader, "no-header", false, "with --format csv, format the first row instead of keeping it as a header")
+}
+
+// lookupFormat returns the handler for path: the one format names, or
+// else the one detected from path's extension.
+func lookupFormat(path, format string, opts formats.Options) (formats.Handler, error) {
+	if format == "" {
+		format = formats.Detect(path)
+	}
+	handler, ok := formats.Lookup(format, opts)
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (want one of: %s)", format, strings.Join(formats.Names(), ", "))
+	}
+	return handler, nil
+}
+
+// parseColumns parses a list of column numbers such as "2,5".
+func parseColumns(v string) ([]int, error) {
+	var cols []int
+	for _, field := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid column %q: want a number from 1", field)
+		}
+		cols = append(cols, n)
+	}
+	return cols, nil
+}
+
+// options holds the command-line settings for one run.
+type options struct {
+	pipeline.Options
+	// Format names the document handler; empty means detect it from the
+	// input file's extension
+	Format        string
+	FormatOptions formats.Options
+	// Report names the format of a report on what was changed, written to
+	// reportTo (standard output if nil); empty means no report
+	Report   string
+	reportTo io.Writer
+	// LineEndings converts the output's line endings, as for
+	// config.ConvertLineEndings
+	LineEndings string
+	// InPlace allows the output file to be the input file
+	InPlace bool
+	// Backup is appended to the name of a file about to be overwritten to
+	// keep its old content; empty means no backup
+	Backup string
+	// AllowCommands lets the configuration file's command stages run
+	AllowCommands bool
+	// onRun, if set, is called with each run of prose once it is formatted
+	onRun func(text string)
+	// set holds the names of the flags given on the command line, which
+	// win over the configuration file
+	set map[string]bool
+}
+
 func run(inPath, outPath string) error {
+	return runWithOptions(context.Background(), inPath, outPath, options{})
+}
+
+// runWithOptions formats inPath into outPath, which must be another file
+// unless opts.InPlace is set. Cancelling ctx stops the formatting, and
+// nothing is written.
+func runWithOptions(ctx context.Context, inPath, outPath string, opts options) error {
 	logger.Info(fmt.Sprintf("Processing file: %s -> %s", inPath, outPath))
 
+	if !opts.InPlace && sameFile(inPath, outPath) {
+		return fmt.Errorf("%s is both the input and the output file; use -w to overwrite it", inPath)
+	}
+
+	if opts.Report != "" {
+		if !slices.Contains(report.Formats(), opts.Report) {
+			return fmt.Errorf("unknown report format %q (want one of: %s)", opts.Report, strings.Join(report.Formats(), ", "))
+		}
+		if opts.Jobs > 0 {
+			return errors.New("--jobs cannot be combined with --report")
+		}
+	}
+
 	input, err := os.ReadFile(inPath)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Failed to read input file: %v", err))
 		return fmt.Errorf("reading input: %w", err)
 	}
 
-	// Tokenize
-	tokens := tokenizer.Tokenize(string(input))
-	logger.Debug(fmt.Sprintf("Tokenized into %d tokens", len(tokens)))
-
-	// Process through pipeline
-	pl := pipeline.New()
-	processed := pl.Process(tokens)
-	logger.Debug(fmt.Sprintf("Pipeline processed %d tokens", len(processed)))
+	// For a report, each run of prose the handler formats is located in
+	// the input, and the changes made to it are moved there
+	var (
+		rec     explain.Recorder
+		changes []explain.Change
+		runs    []formats.Run
+		loc     *report.Locator
+	)
+	if opts.Report != "" {
+		tracker := formats.NewTracker(string(input))
+		loc = report.NewLocator(string(input))
+		opts.Trace = rec.Trace
+		opts.onRun = func(text string) {
+			run := tracker.Locate(text)
+			runs = append(runs, run)
+			changes = append(changes, loc.Changes(run, rec.Changes)...)
+			rec.Changes = nil
+		}
+	}
 
-	// Rebuild string from tokens
-	var result string
-	for _, t := range processed {
-		result += t.Value
+	result, pl, err := formatFile(ctx, inPath, input, opts)
+	if err != nil {
+		return err
 	}
+	logger.Debug(fmt.Sprintf("Formatted %d bytes", len(input)))
 
 	// Write output
-	if err := os.WriteFile(outPath, []byte(result), 0644); err != nil {
+	if err := writeFile(outPath, []byte(result), opts.Backup); err != nil {
 		logger.Error(fmt.Sprintf("Failed to write output file: %v", err))
 		return fmt.Errorf("writing output: %w", err)
 	}
 
+	if opts.Report != "" {
+		var diags []report.Diagnostic
+		for _, run := range runs {
+			found := report.Paragraphs(pl, run.Text, func(tokens []tokenizer.Token) []report.Diagnostic {
+				return append(report.Diagnose(tokens, pl.Macros()...), report.Check(pl.Stages(), tokens)...)
+			})
+			diags = append(diags, loc.Diagnostics(run, found)...)
+		}
+		file := report.NewFile(inPath, outPath, changes, diags)
+		w := opts.reportTo
+		if w == nil {
+			w = os.Stdout
+		}
+		if err := report.Write(w, opts.Report, report.Report{Files: []report.File{file}}); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+	}
+
 	logger.Info("Processing completed successfully")
 	return nil
 }
+
+// formatFile formats src, the content of path, with the configuration
+// that governs path on top of opts, in the format opts names or else the
+// one detected from path, and converts its line endings. It also returns
+// the pipeline it used.
+func formatFile(ctx context.Context, path string, src []byte, opts options) (string, *pipeline.Pipeline, error) {
+	cfg, err := configure(path, &opts.Options, opts.set, opts.AllowCommands)
+	if err != nil {
+		return "", nil, err
+	}
+	if cfg.LineEndings != "" && !opts.set["line-endings"] {
+		opts.LineEndings = cfg.LineEndings
+	}
+
+	pl, err := pipeline.NewWithOptions(opts.Options)
+	if err != nil {
+		return "", nil, fmt.Errorf("configuring pipeline: %w", err)
+	}
+
+	if opts.Format == "" {
+		opts.Format = formats.Detect(path)
+	}
+	handler, err := lookupFormat(path, opts.Format, opts.FormatOptions)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Format the prose runs of the document through the pipeline
+	result, err := formatWith(ctx, handler, string(src), pl, opts.onRun)
+	if err != nil {
+		return "", nil, fmt.Errorf("formatting %s: %w", opts.Format, err)
+	}
+	return config.ConvertLineEndings(result, opts.LineEndings), pl, nil
+}
+
+// formatWith runs handler over src with pl formatting the prose, and calls
+// onRun, if set, with each run formatted. It fails with the first error of
+// the pipeline, which it stops using from then on.
+func formatWith(ctx context.Context, handler formats.Handler, src string, pl *pipeline.Pipeline, onRun func(string)) (string, error) {
+	var stageErr error
+	result, err := handler(src, func(text string) string {
+		if stageErr != nil {
+			return text
+		}
+		out, err := pl.Format(ctx, text)
+		if err != nil {
+			stageErr = err
+			return text
+		}
+		if onRun != nil {
+			onRun(text)
+		}
+		return out
+	})
+	if stageErr != nil {
+		return "", stageErr
+	}
+	return result, err
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"go-reloaded/internal/formats"
+	"go-reloaded/internal/report"
 )
 
 func TestCLI_Run(t *testing.T) {
@@ -33,3 +42,140 @@ func TestCLI_Run(t *testing.T) {
 		t.Errorf("got %q, want %q", got, want)
 	}
 }
+
+func TestCLI_RunCSVColumns(t *testing.T) {
+	tmpDir := t.TempDir()
+	inPath := filepath.Join(tmpDir, "in.csv")
+	outPath := filepath.Join(tmpDir, "out.csv")
+
+	input := "id,a item,note\n1,a apple,\"hello ,world\"\n"
+	if err := os.WriteFile(inPath, []byte(input), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	cols, err := parseColumns("3")
+	if err != nil {
+		t.Fatalf("parseColumns() error = %v", err)
+	}
+	opts := options{FormatOptions: formats.Options{Columns: cols}}
+	if err := runWithOptions(context.Background(), inPath, outPath, opts); err != nil {
+		t.Fatalf("runWithOptions() error = %v", err)
+	}
+
+	out, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("reading out: %v", err)
+	}
+	want := "id,a item,note\n1,a apple,\"hello, world\"\n"
+	if got := string(out); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"0", "x", "2,,3"} {
+		if _, err := parseColumns(bad); err == nil {
+			t.Errorf("parseColumns(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCLI_RunReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	inPath := filepath.Join(tmpDir, "in.txt")
+	outPath := filepath.Join(tmpDir, "out.txt")
+
+	if err := os.WriteFile(inPath, []byte("a amazing (upx) idea"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	var buf bytes.Buffer
+	opts := options{Report: "json", reportTo: &buf}
+	if err := runWithOptions(context.Background(), inPath, outPath, opts); err != nil {
+		t.Fatalf("runWithOptions() error = %v", err)
+	}
+
+	var got report.Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("report is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(got.Files))
+	}
+	f := got.Files[0]
+	if f.Path != inPath || f.Output != outPath {
+		t.Errorf("paths = %q, %q", f.Path, f.Output)
+	}
+	if f.Counts.ByStage["ArticleProcessor"] != 1 {
+		t.Errorf("ArticleProcessor changes = %d, want 1", f.Counts.ByStage["ArticleProcessor"])
+	}
+	if f.Counts.ByRule[report.RuleMalformedMarker] != 1 {
+		t.Errorf("diagnostics = %+v, want one malformed marker", f.Diagnostics)
+	}
+
+	opts = options{Report: "xml", reportTo: &buf}
+	if err := runWithOptions(context.Background(), inPath, outPath, opts); err == nil {
+		t.Error("expected error for unknown report format")
+	}
+
+	// Only prose is reported, at its position in the file
+	mdPath := filepath.Join(tmpDir, "in.md")
+	if err := os.WriteFile(mdPath, []byte("# Notes\n\n```\nx ,y (upx)\n```\n\nwe saw\na amazing (upx) idea\n"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	buf.Reset()
+	opts = options{Report: "json", reportTo: &buf}
+	if err := runWithOptions(context.Background(), mdPath, outPath, opts); err != nil {
+		t.Fatalf("runWithOptions() error = %v", err)
+	}
+	got = report.Report{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("report is not valid JSON: %v\n%s", err, buf.String())
+	}
+	var positions []string
+	for _, c := range got.Files[0].Changes {
+		positions = append(positions, fmt.Sprintf("%s %d:%d", c.Stage, c.Position.Line, c.Position.Column))
+	}
+	for _, d := range got.Files[0].Diagnostics {
+		positions = append(positions, fmt.Sprintf("%s %d:%d", d.Rule, d.Position.Line, d.Position.Column))
+	}
+	want := []string{"ArticleProcessor 8:1", report.RuleArticleMismatch + " 8:1", report.RuleMalformedMarker + " 8:11"}
+	if !reflect.DeepEqual(positions, want) {
+		t.Errorf("positions = %v, want %v", positions, want)
+	}
+}
+
+func TestCLI_RunJobs(t *testing.T) {
+	tmpDir := t.TempDir()
+	inPath := filepath.Join(tmpDir, "in.txt")
+	if err := os.WriteFile(inPath, []byte(strings.Repeat("a amazing ,idea (up)\n\n", 20)), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	var outputs []string
+	for _, jobs := range []int{0, 1, 4} {
+		outPath := filepath.Join(tmpDir, "out.txt")
+		opts := options{}
+		opts.Jobs = jobs
+		if err := runWithOptions(context.Background(), inPath, outPath, opts); err != nil {
+			t.Fatalf("runWithOptions() error = %v", err)
+		}
+		out, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("reading out: %v", err)
+		}
+		outputs = append(outputs, string(out))
+	}
+	if want := strings.Repeat("an amazing, IDEA \n\n", 20); outputs[0] != want {
+		t.Errorf("got %q, want %q", outputs[0], want)
+	}
+	for i, jobs := range []int{1, 4} {
+		if outputs[i+1] != outputs[0] {
+			t.Errorf("--jobs %d gives %q, want %q as without --jobs", jobs, outputs[i+1], outputs[0])
+		}
+	}
+
+	opts := options{Report: "json", reportTo: io.Discard}
+	opts.Jobs = 2
+	if err := runWithOptions(context.Background(), inPath, filepath.Join(tmpDir, "out.txt"), opts); err == nil {
+		t.Error("expected error for --jobs with --report")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2024 go-reloaded contributors
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/pkg/tokenizer"
+)
+
+const replHelp = `Type a line of text to see how it is tokenized and what each stage does.
+Commands:
+  :help   show this help
+  :quit   exit (or press Ctrl+D)
+`
+
+func runREPL(args []string) int {
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	var opts pipeline.Options
+	var allowCommands bool
+	addPipelineFlags(flags, &opts, &allowCommands)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s repl [flags]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 1
+	}
+
+	// There is no input file, so use the current directory's configuration
+	if _, err := configure(".", &opts, flagsSet(flags), allowCommands); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	pl, err := pipeline.NewWithOptions(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: configuring pipeline: %v\n", err)
+		return 1
+	}
+	if err := repl(os.Stdin, os.Stdout, pl); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// repl reads lines from in and, for each, writes its tokens, the text after
+// every pipeline stage and the final result to out.
+func repl(in io.Reader, out io.Writer, pl *pipeline.Pipeline) error {
+	fmt.Fprint(out, "textfmt repl; type :help for help\n> ")
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch strings.TrimSpace(line) {
+		case "":
+		case ":quit", ":q", ":exit":
+			return nil
+		case ":help", ":h", "?":
+			fmt.Fprint(out, replHelp)
+		default:
+			explainLine(out, pl, line)
+		}
+		fmt.Fprint(out, "> ")
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+// explainLine writes the tokens of line and the output of each stage.
+func explainLine(out io.Writer, pl *pipeline.Pipeline, line string) {
+	tokens := pl.Tokenize(line)
+	fmt.Fprintf(out, "tokens (%d):\n", len(tokens))
+	for _, tok := range tokens {
+		fmt.Fprintf(out, "  %-9s %q\n", tokenizer.TypeName(tok.Type), tok.Value)
+	}
+
+	stages := pl.Stages()
+	width := 0
+	for _, stage := range stages {
+		width = max(width, len(stage.Name))
+	}
+
+	fmt.Fprintln(out, "stages:")
+	prev := joinTokens(tokens)
+	for _, stage := range stages {
+		// Stages may edit their input in place; keep each step's tokens intact
+		next, err := stage.Processor.Process(context.Background(), append([]tokenizer.Token(nil), tokens...))
+		if err != nil {
+			fmt.Fprintf(out, "  %-*s  error: %v\n", width, stage.Name, err)
+			continue
+		}
+		tokens = next
+		text := joinTokens(tokens)
+		if text == prev {
+			fmt.Fprintf(out, "  %-*s  (no change)\n", width, stage.Name)
+		} else {
+			fmt.Fprintf(out, "  %-*s  %q\n", width, stage.Name, text)
+		}
+		prev = text
+	}
+	fmt.Fprintf(out, "result: %s\n", prev)
+}
+
+// joinTokens concatenates the values of tokens.
+func joinTokens(tokens []tokenizer.Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(t.Value)
+	}
+	return b.String()
+}
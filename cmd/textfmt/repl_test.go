@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+)
+
+func TestREPL(t *testing.T) {
+	in := strings.NewReader("a (up) apple\n\n:quit\nnever read\n")
+	var out bytes.Buffer
+	if err := repl(in, &out, pipeline.New()); err != nil {
+		t.Fatalf("repl() error = %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		`Marker    "(up)"`,
+		`CaseProcessor         "A  apple"`,
+		"HexBinProcessor       (no change)",
+		`ArticleProcessor      "An  apple"`,
+		"result: An apple",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("repl output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "never") {
+		t.Errorf("repl kept reading after :quit:\n%s", got)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2024 go-reloaded contributors
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go-reloaded/internal/formats"
+	"go-reloaded/internal/logger"
+	"go-reloaded/internal/pipeline"
+)
+
+// watchExtensions lists the files watch mode reformats, and batch mode
+// looks for in directories.
+var watchExtensions = map[string]bool{".txt": true, ".md": true}
+
+// maxLoggedLines caps the changed lines logged per reformat.
+const maxLoggedLines = 10
+
+func runWatch(args []string) int {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	opts := addFlags(flags)
+	interval := flags.Duration("interval", 500*time.Millisecond, "how often to check for changes")
+	debounce := flags.Duration("debounce", 300*time.Millisecond, "how long a file must stay unchanged before it is reformatted")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s watch [flags] <dir>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 1
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	opts.set = flagsSet(flags)
+	w, err := newWatcher(flags.Arg(0), *opts, *debounce, logger.New(os.Stderr))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := w.run(ctx, *interval); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// fileState is what the watcher last saw of a file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// watcher polls a directory tree and reformats .txt and .md files in place
+// once they have stopped changing for the debounce period.
+type watcher struct {
+	dir      string
+	opts     options
+	debounce time.Duration
+	log      *logger.Logger
+
+	seen    map[string]fileState
+	pending map[string]time.Time // when each changed file last changed
+	written map[string]string    // content last written by the watcher
+}
+
+func newWatcher(dir string, opts options, debounce time.Duration, log *logger.Logger) (*watcher, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("watching directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("watching directory: %s is not a directory", dir)
+	}
+	if _, err := pipeline.NewWithOptions(opts.Options); err != nil {
+		return nil, fmt.Errorf("configuring pipeline: %w", err)
+	}
+	if opts.Format != "" {
+		if _, ok := formats.Lookup(opts.Format, opts.FormatOptions); !ok {
+			return nil, fmt.Errorf("unknown format %q (want one of: %s)", opts.Format, strings.Join(formats.Names(), ", "))
+		}
+	}
+
+	return &watcher{
+		dir:      dir,
+		opts:     opts,
+		debounce: debounce,
+		log:      log,
+		seen:     make(map[string]fileState),
+		pending:  make(map[string]time.Time),
+		written:  make(map[string]string),
+	}, nil
+}
+
+// run polls every interval until ctx is cancelled. Files that exist when
+// it starts are only reformatted once they change.
+func (w *watcher) run(ctx context.Context, interval time.Duration) error {
+	if err := w.scan(ctx, time.Now(), false); err != nil {
+		return err
+	}
+	w.log.Info(fmt.Sprintf("Watching %s for changes to %d file(s)", w.dir, len(w.seen)))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			w.log.Info("Stopped watching")
+			return nil
+		case now := <-ticker.C:
+			if err := w.scan(ctx, now, true); err != nil {
+				w.log.Error(err.Error())
+			}
+		}
+	}
+}
+
+// scan looks for new or modified files and, if reformat is set, reformats
+// those that have been quiet for the debounce period as of now.
+func (w *watcher) scan(ctx context.Context, now time.Time, reformat bool) error {
+	present := make(map[string]bool)
+	err := filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != w.dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !watchExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed while walking
+		}
+
+		present[path] = true
+		state := fileState{info.ModTime(), info.Size()}
+		if old, ok := w.seen[path]; !ok || old != state {
+			w.seen[path] = state
+			if reformat {
+				w.pending[path] = now // every save restarts the debounce
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("scanning %s: %w", w.dir, err)
+	}
+
+	for path := range w.seen {
+		if !present[path] {
+			delete(w.seen, path)
+			delete(w.pending, path)
+			delete(w.written, path)
+		}
+	}
+
+	for path, changed := range w.pending {
+		if now.Sub(changed) < w.debounce {
+			continue
+		}
+		delete(w.pending, path)
+		if err := w.reformat(ctx, path); err != nil {
+			w.log.Error(err.Error())
+		}
+	}
+	return nil
+}
+
+// reformat rewrites one file in place and logs the lines that changed.
+func (w *watcher) reformat(ctx context.Context, path string) error {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if written, ok := w.written[path]; ok && written == string(input) {
+		return nil // our own write
+	}
+
+	// The configuration file may differ between directories, and change
+	// while watching, so read it for every file
+	result, _, err := formatFile(ctx, path, input, w.opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	w.written[path] = result
+	if result == string(input) {
+		return nil
+	}
+
+	if err := writeFile(path, []byte(result), w.opts.Backup); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		w.seen[path] = fileState{info.ModTime(), info.Size()}
+	}
+
+	w.logChanges(path, string(input), result)
+	return nil
+}
+
+// logChanges logs which lines of path a reformat changed.
+func (w *watcher) logChanges(path, before, after string) {
+	oldLines := strings.Split(before, "\n")
+	newLines := strings.Split(after, "\n")
+
+	var changed []string
+	for i := 0; i < len(oldLines) && i < len(newLines); i++ {
+		if oldLines[i] != newLines[i] {
+			changed = append(changed, fmt.Sprintf("  %s:%d: %q -> %q", path, i+1, oldLines[i], newLines[i]))
+		}
+	}
+
+	w.log.Info(fmt.Sprintf("Reformatted %s: %d line(s) changed", path, len(changed)))
+	for i, line := range changed {
+		if i == maxLoggedLines {
+			w.log.Info(fmt.Sprintf("  ... and %d more", len(changed)-maxLoggedLines))
+			break
+		}
+		w.log.Info(line)
+	}
+}
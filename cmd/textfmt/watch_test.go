@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-reloaded/internal/logger"
+)
+
+// touch writes content to path and moves its modification time to mtime so
+// the change is seen regardless of the file system's timestamp resolution.
+func touch(t *testing.T, path, content string, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("failed to set times on %s: %v", path, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestWatcher_ReformatsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	txtPath := filepath.Join(dir, "notes.txt")
+	mdPath := filepath.Join(dir, "sub", "doc.md")
+	goPath := filepath.Join(dir, "main.go")
+	if err := os.MkdirAll(filepath.Dir(mdPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	touch(t, txtPath, "old ,text", start)
+	touch(t, goPath, "package main // a , b\n", start)
+
+	var logs bytes.Buffer
+	w, err := newWatcher(dir, options{}, time.Second, logger.New(&logs))
+	if err != nil {
+		t.Fatalf("newWatcher() error = %v", err)
+	}
+	if err := w.scan(context.Background(), start, false); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+	if got := readFile(t, txtPath); got != "old ,text" {
+		t.Errorf("existing file reformatted on startup: %q", got)
+	}
+
+	// Save twice in quick succession; only the last save counts
+	touch(t, txtPath, "a apple ,here", start.Add(time.Second))
+	touch(t, mdPath, "# a idea ,here\n\n    code , block\n", start.Add(time.Second))
+	touch(t, goPath, "package main // c , d\n", start.Add(time.Second))
+	if err := w.scan(context.Background(), start.Add(time.Second), true); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+	touch(t, txtPath, "a apple ,there", start.Add(2*time.Second))
+	if err := w.scan(context.Background(), start.Add(1500*time.Millisecond), true); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+	if got := readFile(t, txtPath); got != "a apple ,there" {
+		t.Errorf("file reformatted before the debounce period: %q", got)
+	}
+
+	if err := w.scan(context.Background(), start.Add(3*time.Second), true); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+	if got, want := readFile(t, txtPath), "an apple, there"; got != want {
+		t.Errorf("notes.txt = %q, want %q", got, want)
+	}
+	if got, want := readFile(t, mdPath), "# an idea, here\n\n    code , block\n"; got != want {
+		t.Errorf("doc.md = %q, want %q", got, want)
+	}
+	if got, want := readFile(t, goPath), "package main // c , d\n"; got != want {
+		t.Errorf("main.go = %q, want %q", got, want)
+	}
+	if !strings.Contains(logs.String(), `notes.txt:1: "a apple ,there" -> "an apple, there"`) {
+		t.Errorf("log does not show the changed line:\n%s", logs.String())
+	}
+
+	// The watcher's own writes do not trigger another reformat
+	logs.Reset()
+	if err := w.scan(context.Background(), start.Add(10*time.Second), true); err != nil {
+		t.Fatalf("scan() error = %v", err)
+	}
+	if len(w.pending) != 0 || logs.Len() != 0 {
+		t.Errorf("own write picked up as a change: pending=%v log=%q", w.pending, logs.String())
+	}
+}
+
+func TestNewWatcher_Errors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	touch(t, file, "x", time.Now())
+
+	for _, dir := range []string{file, filepath.Join(t.TempDir(), "missing")} {
+		if _, err := newWatcher(dir, options{}, 0, logger.New(&bytes.Buffer{})); err == nil {
+			t.Errorf("newWatcher(%q) should fail", dir)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024 go-reloaded contributors
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeFile replaces the file at path with data without ever leaving it
+// half written: data goes to a temporary file in the same directory, which
+// is synced and then renamed over path. An existing file keeps its mode
+// and, where the system allows it, its owner; a new one gets mode 0644.
+// If backup is not empty, the old content is first saved next to the file
+// under its name plus backup, e.g. "notes.txt.orig". A symbolic link is
+// followed and its target replaced.
+func writeFile(path string, data []byte, backup string) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return replaceFile(path, data, nil)
+	case err != nil:
+		return err
+	case !info.Mode().IsRegular():
+		// Devices and pipes such as /dev/stdout cannot be renamed over
+		return os.WriteFile(path, data, 0644)
+	}
+
+	if backup != "" {
+		old, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := replaceFile(path+backup, old, info); err != nil {
+			return fmt.Errorf("backing up %s: %w", path, err)
+		}
+	}
+	return replaceFile(path, data, info)
+}
+
+// replaceFile atomically replaces path with data, giving it the mode and
+// owner of like, or mode 0644 if like is nil.
+func replaceFile(path string, data []byte, like fs.FileInfo) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	mode := fs.FileMode(0644)
+	if like != nil {
+		// Changing the owner clears the setuid and setgid bits, so it
+		// goes first
+		chown(tmp, like)
+		mode = like.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// Make the rename itself survive a crash
+	return syncDir(dir)
+}
+
+// sameFile reports whether a and b name the same file. Paths that do not
+// exist are compared by name.
+func sameFile(a, b string) bool {
+	ai, aerr := os.Stat(a)
+	bi, berr := os.Stat(b)
+	if aerr == nil && berr == nil {
+		return os.SameFile(ai, bi)
+	}
+	absA, aerr := filepath.Abs(a)
+	absB, berr := filepath.Abs(b)
+	return aerr == nil && berr == nil && absA == absB
+}
@@ -0,0 +1,19 @@
+// Copyright (c) 2024 go-reloaded contributors
+// Licensed under the MIT License. See LICENSE file for details.
+
+//go:build !unix
+
+package main
+
+import (
+	"io/fs"
+	"os"
+)
+
+// chown does nothing: files have no Unix owner here.
+func chown(f *os.File, like fs.FileInfo) {}
+
+// syncDir does nothing: directories cannot be opened for syncing here.
+func syncDir(dir string) error {
+	return nil
+}
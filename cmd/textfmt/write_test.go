@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dirNames lists the names in dir, sorted.
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestWriteFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   fs.FileMode // of the existing file; zero means none
+		backup string
+		want   fs.FileMode
+		files  []string
+	}{
+		{name: "new file", want: 0644, files: []string{"out.txt"}},
+		{name: "keeps mode", mode: 0600, want: 0600, files: []string{"out.txt"}},
+		{name: "keeps executable mode", mode: 0755, want: 0755, files: []string{"out.txt"}},
+		{name: "backup", mode: 0640, backup: ".orig", want: 0640, files: []string{"out.txt", "out.txt.orig"}},
+		{name: "no backup of a new file", backup: ".orig", want: 0644, files: []string{"out.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "out.txt")
+			if tt.mode != 0 {
+				touch(t, path, "old", time.Now())
+				if err := os.Chmod(path, tt.mode); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := writeFile(path, []byte("new"), tt.backup); err != nil {
+				t.Fatalf("writeFile() error = %v", err)
+			}
+
+			if got := readFile(t, path); got != "new" {
+				t.Errorf("content = %q, want %q", got, "new")
+			}
+			if got := dirNames(t, dir); !reflect.DeepEqual(got, tt.files) {
+				t.Errorf("files = %v, want %v", got, tt.files)
+			}
+			if runtime.GOOS == "windows" {
+				return
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := info.Mode().Perm(); got != tt.want {
+				t.Errorf("mode = %v, want %v", got, tt.want)
+			}
+			if tt.backup != "" && tt.mode != 0 {
+				if got := readFile(t, path+tt.backup); got != "old" {
+					t.Errorf("backup = %q, want %q", got, "old")
+				}
+				info, err := os.Stat(path + tt.backup)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := info.Mode().Perm(); got != tt.mode {
+					t.Errorf("backup mode = %v, want %v", got, tt.mode)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFile_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	link := filepath.Join(dir, "link.txt")
+	touch(t, target, "old", time.Now())
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("cannot create symbolic links: %v", err)
+	}
+
+	if err := writeFile(link, []byte("new"), ""); err != nil {
+		t.Fatalf("writeFile() error = %v", err)
+	}
+	if got := readFile(t, target); got != "new" {
+		t.Errorf("target = %q, want %q", got, "new")
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("link replaced by a regular file")
+	}
+}
+
+func TestWriteFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "missing", "out.txt")
+	if err := writeFile(path, []byte("new"), ""); err == nil {
+		t.Errorf("writeFile() into a missing directory should fail")
+	}
+}
+
+func TestCLI_RunSameFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "in.txt")
+	touch(t, path, "a apple", time.Now())
+
+	// The same file under another name
+	other := filepath.Join(dir, "alias.txt")
+	if err := os.Link(path, other); err != nil {
+		t.Skipf("cannot create hard links: %v", err)
+	}
+	if err := runWithOptions(context.Background(), path, other, options{}); err == nil || !strings.Contains(err.Error(), "-w") {
+		t.Errorf("runWithOptions() error = %v, want one suggesting -w", err)
+	}
+	if got := readFile(t, path); got != "a apple" {
+		t.Errorf("input overwritten without -w: %q", got)
+	}
+
+	opts := options{InPlace: true, Backup: ".orig"}
+	if err := runWithOptions(context.Background(), path, path, opts); err != nil {
+		t.Fatalf("runWithOptions() error = %v", err)
+	}
+	if got := readFile(t, path); got != "an apple" {
+		t.Errorf("in.txt = %q, want %q", got, "an apple")
+	}
+	if got := readFile(t, path+".orig"); got != "a apple" {
+		t.Errorf("in.txt.orig = %q, want %q", got, "a apple")
+	}
+}
@@ -0,0 +1,325 @@
+// Package config reads .textfmt.json configuration files.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/pkg/processors"
+	"go-reloaded/pkg/tokenizer"
+)
+
+// FileName is the name of a configuration file. The file that governs an
+// input is the nearest one in its directory or a parent directory.
+const FileName = ".textfmt.json"
+
+// Line ending styles.
+const (
+	LineEndingsKeep = "keep" // leave line endings as they are
+	LineEndingsLF   = "lf"
+	LineEndingsCRLF = "crlf"
+)
+
+// Config holds the settings of a configuration file. Omitted settings keep
+// their defaults.
+type Config struct {
+	// Stages names the stages to run, from pipeline.StageNames.
+	Stages []string `json:"stages,omitempty"`
+	// Plugins names registered plugin stages to run on top of Stages.
+	Plugins []string `json:"plugins,omitempty"`
+	// Locale selects the punctuation spacing table, e.g. "fr".
+	Locale string `json:"locale,omitempty"`
+	// DashStyle is "spaced" or "unspaced".
+	DashStyle string `json:"dashStyle,omitempty"`
+	// SentenceCase capitalizes the first word of every sentence.
+	SentenceCase bool `json:"sentenceCase,omitempty"`
+	// ArticleExceptions are words after which "a" is never changed to "an".
+	ArticleExceptions []string `json:"articleExceptions,omitempty"`
+	// Protected are regular expressions for text that must not change.
+	Protected []string `json:"protected,omitempty"`
+	// Markers are user-defined markers, keyed by marker, e.g. "(sig)".
+	Markers map[string]Marker `json:"markers,omitempty"`
+	// Commands are external commands that run as stages, keyed by stage
+	// name.
+	Commands map[string]Command `json:"commands,omitempty"`
+	// LineEndings converts the output's line endings: keep, lf or crlf.
+	LineEndings string `json:"lineEndings,omitempty"`
+	// Lint selects the rules textfmt lint checks.
+	Lint Lint `json:"lint,omitzero"`
+}
+
+// Lint holds the rule names to turn off and back on, as for the lint
+// command's --disable and --enable flags.
+type Lint struct {
+	Disable []string `json:"disable,omitempty"`
+	Enable  []string `json:"enable,omitempty"`
+}
+
+// Marker defines a user-defined marker. In a file it is either the text
+// the marker expands to or an object with a pattern that rewrites the
+// words before the marker, as processors.Macro does.
+type Marker struct {
+	Text    string `json:"text,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Replace string `json:"replace,omitempty"`
+	Words   int    `json:"words,omitempty"`
+}
+
+// marker has the fields of Marker without its JSON methods.
+type marker Marker
+
+func (m *Marker) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*m = Marker{}
+		return json.Unmarshal(data, &m.Text)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*marker)(m))
+}
+
+func (m Marker) MarshalJSON() ([]byte, error) {
+	var v any = marker(m)
+	if m.Pattern == "" && m.Replace == "" && m.Words == 0 {
+		v = m.Text
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(b.Bytes()), nil
+}
+
+// Command defines an external command stage, see
+// processors.CommandProcessor.
+type Command struct {
+	// Run is the command and its arguments. A relative command path is
+	// resolved against the directory of the configuration file, where the
+	// command also runs.
+	Run []string `json:"run"`
+	// Input is "text", the default, or "tokens" for a JSON token stream.
+	Input string `json:"input,omitempty"`
+	// Timeout bounds each run, e.g. "2s"; the default is 10s.
+	Timeout string `json:"timeout,omitempty"`
+	// Before and After name the stages the command must run before and
+	// after; with neither it runs last.
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+
+	// dir is the directory of the configuration file
+	dir string
+}
+
+// Command inputs.
+const (
+	InputText   = "text"
+	InputTokens = "tokens"
+)
+
+// commands converts the command stages for the pipeline, sorted by name
+// so that commands placed alike run in a stable order.
+func (c Config) commands() ([]pipeline.Command, error) {
+	names := make([]string, 0, len(c.Commands))
+	for name := range c.Commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var cmds []pipeline.Command
+	for _, name := range names {
+		cmd := c.Commands[name]
+		if len(cmd.Run) == 0 || cmd.Run[0] == "" {
+			return nil, fmt.Errorf("command %q: run must name a command", name)
+		}
+		if cmd.Input != "" && cmd.Input != InputText && cmd.Input != InputTokens {
+			return nil, fmt.Errorf("command %q: unknown input %q (want text or tokens)", name, cmd.Input)
+		}
+		var timeout time.Duration
+		if cmd.Timeout != "" {
+			var err error
+			if timeout, err = time.ParseDuration(cmd.Timeout); err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("command %q: invalid timeout %q", name, cmd.Timeout)
+			}
+		}
+		cmds = append(cmds, pipeline.Command{
+			CommandProcessor: processors.CommandProcessor{
+				Name:    name,
+				Args:    cmd.Run,
+				Dir:     cmd.dir,
+				JSON:    cmd.Input == InputTokens,
+				Timeout: timeout,
+			},
+			Before: cmd.Before,
+			After:  cmd.After,
+		})
+	}
+	return cmds, nil
+}
+
+// markerName matches the name of a user-defined marker.
+var markerName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// builtinMarkers are the names user-defined markers may not take.
+var builtinMarkers = []string{"up", "low", "cap", "hex", "bin"}
+
+// macros converts the user-defined markers for the pipeline.
+func (c Config) macros() (map[string]processors.Macro, error) {
+	if len(c.Markers) == 0 {
+		return nil, nil
+	}
+	macros := make(map[string]processors.Macro, len(c.Markers))
+	for key, m := range c.Markers {
+		name, _ := tokenizer.MarkerName(key)
+		if !markerName.MatchString(name) || slices.Contains(builtinMarkers, name) {
+			return nil, fmt.Errorf("invalid marker %q: want a new name such as \"(sig)\"", key)
+		}
+		if _, dup := macros[name]; dup {
+			return nil, fmt.Errorf("marker %q is defined twice", key)
+		}
+		macro := processors.Macro{Text: m.Text, Replace: m.Replace, Words: m.Words}
+		switch {
+		case m.Pattern == "" && (m.Replace != "" || m.Words != 0):
+			return nil, fmt.Errorf("marker %q: replace and words need a pattern", key)
+		case m.Pattern != "" && m.Text != "":
+			return nil, fmt.Errorf("marker %q: want either text or a pattern, not both", key)
+		case m.Words < 0:
+			return nil, fmt.Errorf("marker %q: words must not be negative", key)
+		case m.Pattern != "":
+			re, err := regexp.Compile(m.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("marker %q: %w", key, err)
+			}
+			macro.Pattern = re
+		}
+		macros[name] = macro
+	}
+	return macros, nil
+}
+
+// Load reads and checks the configuration file at path. Unknown settings
+// are an error, so that a misspelled one is not silently ignored.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var c Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return Config{}, err
+	}
+	for name, cmd := range c.Commands {
+		cmd.dir = dir
+		c.Commands[name] = cmd
+	}
+	if err := c.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c Config) validate() error {
+	if c.LineEndings != "" && !slices.Contains([]string{LineEndingsKeep, LineEndingsLF, LineEndingsCRLF}, c.LineEndings) {
+		return fmt.Errorf("unknown line endings %q (want keep, lf or crlf)", c.LineEndings)
+	}
+	// Building a pipeline checks the stage names, locale, dash style and
+	// patterns
+	opts, err := c.Pipeline()
+	if err != nil {
+		return err
+	}
+	if _, err := pipeline.NewWithOptions(opts); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Pipeline returns the pipeline options the configuration sets. It fails
+// if a marker or command is invalid.
+func (c Config) Pipeline() (pipeline.Options, error) {
+	macros, err := c.macros()
+	if err != nil {
+		return pipeline.Options{}, err
+	}
+	commands, err := c.commands()
+	if err != nil {
+		return pipeline.Options{}, err
+	}
+	return pipeline.Options{
+		Locale:            c.Locale,
+		DashStyle:         processors.DashStyle(c.DashStyle),
+		SentenceCase:      c.SentenceCase,
+		Stages:            c.Stages,
+		Plugins:           c.Plugins,
+		ArticleExceptions: c.ArticleExceptions,
+		Protected:         c.Protected,
+		Macros:            macros,
+		Commands:          commands,
+	}, nil
+}
+
+// Find returns the path of the configuration file nearest to dir, looking
+// in dir and then its parents, or "" if there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// For loads the configuration file that governs path, a file or a
+// directory, and returns it with its path. With no configuration file it
+// returns the zero Config and "".
+func For(path string) (Config, string, error) {
+	dir := path
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	found, err := Find(dir)
+	if err != nil || found == "" {
+		return Config{}, "", err
+	}
+	c, err := Load(found)
+	return c, found, err
+}
+
+// ConvertLineEndings rewrites every line ending in s in the given style.
+func ConvertLineEndings(s, style string) string {
+	switch style {
+	case LineEndingsLF:
+		return strings.ReplaceAll(s, "\r\n", "\n")
+	case LineEndingsCRLF:
+		return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+	}
+	return s
+}
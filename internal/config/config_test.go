@@ -0,0 +1,182 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFor(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, FileName), `{"locale": "fr", "lint": {"disable": ["article"]}}`)
+	writeFile(t, filepath.Join(root, "docs", "api", FileName), `{"stages": ["punctuation"], "lineEndings": "crlf"}`)
+	writeFile(t, filepath.Join(root, "docs", "guide.txt"), "")
+	writeFile(t, filepath.Join(root, "docs", "api", "ref", "index.txt"), "")
+
+	tests := []struct {
+		path     string
+		wantFile string
+		want     Config
+	}{
+		{
+			path:     filepath.Join(root, "docs", "guide.txt"),
+			wantFile: filepath.Join(root, FileName),
+			want:     Config{Locale: "fr", Lint: Lint{Disable: []string{"article"}}},
+		},
+		{
+			path:     filepath.Join(root, "docs", "api", "ref", "index.txt"),
+			wantFile: filepath.Join(root, "docs", "api", FileName),
+			want:     Config{Stages: []string{"punctuation"}, LineEndings: LineEndingsCRLF},
+		},
+		{
+			path:     filepath.Join(root, "docs", "api"),
+			wantFile: filepath.Join(root, "docs", "api", FileName),
+			want:     Config{Stages: []string{"punctuation"}, LineEndings: LineEndingsCRLF},
+		},
+		{
+			path:     filepath.Join(root, "missing", "new.txt"),
+			wantFile: filepath.Join(root, FileName),
+			want:     Config{Locale: "fr", Lint: Lint{Disable: []string{"article"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		got, file, err := For(tt.path)
+		if err != nil {
+			t.Fatalf("For(%s) error = %v", tt.path, err)
+		}
+		if file != tt.wantFile || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("For(%s) = %+v from %s, want %+v from %s", tt.path, got, file, tt.want, tt.wantFile)
+		}
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"syntax":       `{"locale": "fr",}`,
+		"unknown key":  `{"locales": "fr"}`,
+		"locale":       `{"locale": "klingon"}`,
+		"stage":        `{"stages": ["spelling"]}`,
+		"pattern":      `{"protected": ["("]}`,
+		"line endings": `{"lineEndings": "cr"}`,
+		"builtin":      `{"markers": {"(up)": "UP"}}`,
+		"marker name":  `{"markers": {"(two words)": "x"}}`,
+		"duplicate":    `{"markers": {"(sig)": "a", "(SIG)": "b"}}`,
+		"text and pat": `{"markers": {"(x)": {"text": "a", "pattern": "b"}}}`,
+		"bad pattern":  `{"markers": {"(x)": {"pattern": "("}}}`,
+		"marker field": `{"markers": {"(x)": {"pattern": "a", "with": "b"}}}`,
+	} {
+		path := filepath.Join(dir, name+".json")
+		writeFile(t, path, content)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%s): expected an error", name)
+		}
+	}
+}
+
+func TestConvertLineEndings(t *testing.T) {
+	in := "one\r\ntwo\nthree"
+	for style, want := range map[string]string{
+		LineEndingsKeep: in,
+		"":              in,
+		LineEndingsLF:   "one\ntwo\nthree",
+		LineEndingsCRLF: "one\r\ntwo\r\nthree",
+	} {
+		if got := ConvertLineEndings(in, style); got != want {
+			t.Errorf("ConvertLineEndings(%q) = %q, want %q", style, got, want)
+		}
+	}
+}
+
+func TestLoad_Markers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeFile(t, path, `{"markers": {
+		"(tm)": "\u2122",
+		"sku": {"pattern": "^\\w+$", "replace": "SKU-$0", "words": 2}
+	}}`)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string]Marker{
+		"(tm)": {Text: "\u2122"},
+		"sku":  {Pattern: `^\w+$`, Replace: "SKU-$0", Words: 2},
+	}
+	if !reflect.DeepEqual(c.Markers, want) {
+		t.Errorf("Markers = %+v, want %+v", c.Markers, want)
+	}
+
+	opts, err := c.Pipeline()
+	if err != nil {
+		t.Fatalf("Pipeline() error = %v", err)
+	}
+	pl, err := pipeline.NewWithOptions(opts)
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	got, err := pl.Format(context.Background(), "Acme(TM) ab cd (sku)")
+	if want := "Acme\u2122 SKU-ab SKU-cd "; err != nil || got != want {
+		t.Errorf("Format() = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestLoad_Commands(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell to run commands with")
+	}
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "scripts", "brands.sh"), "sed 's/IPHONE/iPhone/g'\n")
+	path := filepath.Join(dir, FileName)
+	writeFile(t, path, `{"commands": {
+		"brands": {"run": ["sh", "scripts/brands.sh"], "timeout": "5s", "after": ["case"]}
+	}}`)
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	opts, err := c.Pipeline()
+	if err != nil {
+		t.Fatalf("Pipeline() error = %v", err)
+	}
+	pl, err := pipeline.NewWithOptions(opts)
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	// The script is found relative to the configuration file
+	got, err := pl.Format(context.Background(), "my iphone (up)")
+	if err != nil || got != "my iPhone " {
+		t.Errorf("Format() = %q, %v; want %q", got, err, "my iPhone ")
+	}
+
+	for name, content := range map[string]string{
+		"no run":  `{"commands": {"x": {"run": []}}}`,
+		"input":   `{"commands": {"x": {"run": ["cat"], "input": "xml"}}}`,
+		"timeout": `{"commands": {"x": {"run": ["cat"], "timeout": "soon"}}}`,
+		"name":    `{"commands": {"article": {"run": ["cat"]}}}`,
+		"order":   `{"commands": {"x": {"run": ["cat"], "after": ["spelling"]}}}`,
+		"field":   `{"commands": {"x": {"run": ["cat"], "env": {}}}}`,
+	} {
+		path := filepath.Join(dir, name+".json")
+		writeFile(t, path, content)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%s): expected an error", name)
+		}
+	}
+}
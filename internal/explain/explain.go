@@ -0,0 +1,398 @@
+// Package explain records what each pipeline stage changed and describes
+// the changes in words.
+package explain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/pkg/tokenizer"
+)
+
+// Change kinds.
+const (
+	KindMarker  = "marker"  // a marker was applied and removed
+	KindReplace = "replace" // tokens were replaced
+	KindInsert  = "insert"  // tokens were added
+	KindDelete  = "delete"  // tokens were removed
+)
+
+// Change is one edit a stage made to the token stream.
+type Change struct {
+	Stage string
+	Kind  string
+	// Marker is the marker that caused the change, if any
+	Marker string
+	// Before and After hold the values of the replaced tokens, markers and
+	// spaces excluded
+	Before []string
+	After  []string
+	// Removed and Added hold the values of the tokens only removed or only
+	// added, spaces included
+	Removed []string
+	Added   []string
+	// Original and New are the text of the span before and after
+	Original string
+	New      string
+	// Line and Col locate the change, from 1, in the text the stage
+	// received, or for a Recorder in the text the pipeline received;
+	// EndLine and EndCol mark the end of the original span
+	Line, Col       int
+	EndLine, EndCol int
+}
+
+// Recorder collects the changes of every stage. Its Trace method can be
+// passed as pipeline.Options.Trace. Changes are placed in the text the
+// pipeline received, not the one each stage did, so a marker removed by an
+// early stage does not shift the changes of later ones.
+type Recorder struct {
+	Changes []Change
+	// last is what the previous stage returned, and origins where each of
+	// its tokens, and its end, started in the text the pipeline received
+	last    []tokenizer.Token
+	origins []origin
+	// seen holds the stages traced since the pipeline received the text
+	seen map[string]bool
+}
+
+// origin is a line and column, from 1.
+type origin struct {
+	line, col int
+}
+
+// Trace records the changes one stage made.
+func (r *Recorder) Trace(stage pipeline.Stage, before, after []tokenizer.Token) {
+	// Every stage runs once per text, so a stage seen before, or tokens
+	// other than the last ones, mean the pipeline received a new text
+	if r.seen[stage.Name] || !slices.Equal(before, r.last) {
+		r.origins = origins(before)
+		r.seen = make(map[string]bool)
+	}
+	r.seen[stage.Name] = true
+
+	spans := diff(before, after)
+	for _, s := range spans {
+		c := newChange(stage.Name, before, after, s)
+		c.Line, c.Col = r.origins[s.b0].line, r.origins[s.b0].col
+		c.EndLine, c.EndCol = r.origins[s.b1].line, r.origins[s.b1].col
+		r.Changes = append(r.Changes, c)
+	}
+	r.origins = follow(r.origins, spans, len(after))
+	r.last = after
+}
+
+// origins returns where each of tokens, and their end, starts.
+func origins(tokens []tokenizer.Token) []origin {
+	o := make([]origin, 0, len(tokens)+1)
+	line, col := 1, 1
+	for _, tok := range tokens {
+		o = append(o, origin{line, col})
+		line, col = advance(line, col, tok.Value)
+	}
+	return append(o, origin{line, col})
+}
+
+// follow carries origins, of the tokens a stage received, over to the n
+// tokens it returned given the spans it changed. Unchanged tokens keep
+// theirs; the tokens of a span take those of the span's tokens in turn,
+// and the last one's for any extra.
+func follow(origins []origin, spans []span, n int) []origin {
+	o := make([]origin, 0, n+1)
+	i := 0
+	for _, s := range spans {
+		for ; i < s.b0; i++ {
+			o = append(o, origins[i])
+		}
+		for k := range s.a1 - s.a0 {
+			o = append(o, origins[min(s.b0+k, max(s.b1-1, s.b0))])
+		}
+		i = s.b1
+	}
+	for ; len(o) < n; i++ {
+		o = append(o, origins[i])
+	}
+	return append(o, origins[len(origins)-1])
+}
+
+// resyncWindow bounds how far Diff looks ahead for matching tokens.
+const resyncWindow = 16
+
+// span is a run of differing tokens: before[b0:b1] became after[a0:a1].
+type span struct {
+	b0, b1, a0, a1 int
+}
+
+// Diff compares the tokens a stage received and returned and groups the
+// differences into changes. Edits separated only by unchanged spaces form
+// one change, so a marker and the words it applied to are reported
+// together. The diff is greedy: after a mismatch it resumes at the nearest
+// pair of equal tokens.
+func Diff(stage string, before, after []tokenizer.Token) []Change {
+	spans := diff(before, after)
+	changes := make([]Change, 0, len(spans))
+	for _, s := range spans {
+		changes = append(changes, newChange(stage, before, after, s))
+	}
+	return changes
+}
+
+// diff returns the spans in which before and after differ, for Diff.
+func diff(before, after []tokenizer.Token) []span {
+	var spans []span
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		if i < len(before) && j < len(after) && before[i] == after[j] {
+			i++
+			j++
+			continue
+		}
+		ni, nj := resync(before, after, i, j)
+		spans = append(spans, span{i, ni, j, nj})
+		i, j = ni, nj
+	}
+
+	// Merge spans separated only by unchanged spaces
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && onlySpaces(before[merged[n-1].b1:s.b0]) {
+			merged[n-1].b1, merged[n-1].a1 = s.b1, s.a1
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// resyncRun is how many tokens in a row must agree before Diff trusts a
+// match that starts with a space; single spaces agree far too often to go
+// by.
+const resyncRun = 3
+
+// resync finds the nearest indexes at or after i and j where before and
+// after agree again, or the ends of both streams if they never do.
+func resync(before, after []tokenizer.Token, i, j int) (int, int) {
+	for d := 1; d <= 2*resyncWindow; d++ {
+		for k := 0; k <= d; k++ {
+			bi, aj := i+k, j+d-k
+			if bi < len(before) && aj < len(after) && agree(before[bi:], after[aj:]) {
+				return bi, aj
+			}
+		}
+	}
+	return len(before), len(after)
+}
+
+// agree reports whether before and after start with the same word, or with
+// resyncRun equal tokens, or with equal tokens up to the end of either.
+func agree(before, after []tokenizer.Token) bool {
+	if before[0] == after[0] && before[0].Type != tokenizer.Space {
+		return true
+	}
+	n := min(resyncRun, len(before), len(after))
+	for k := 0; k < n; k++ {
+		if before[k] != after[k] {
+			return false
+		}
+	}
+	return n > 0
+}
+
+func onlySpaces(tokens []tokenizer.Token) bool {
+	for _, tok := range tokens {
+		if tok.Type != tokenizer.Space {
+			return false
+		}
+	}
+	return true
+}
+
+// newChange describes the tokens of span s.
+func newChange(stage string, before, after []tokenizer.Token, s span) Change {
+	c := Change{Stage: stage}
+
+	// Pair up the words that were rewritten in place; the rest were
+	// removed or added. Words the span merely passes over are dropped.
+	var oldWords, newWords []tokenizer.Token
+	for _, tok := range before[s.b0:s.b1] {
+		switch {
+		case tok.Type == tokenizer.Marker && c.Marker == "":
+			c.Marker = tok.Value
+		case tok.Type == tokenizer.Word || tok.Type == tokenizer.Punct:
+			oldWords = append(oldWords, tok)
+		default:
+			c.Removed = append(c.Removed, tok.Value)
+		}
+	}
+	for _, tok := range after[s.a0:s.a1] {
+		if tok.Type == tokenizer.Word || tok.Type == tokenizer.Punct {
+			newWords = append(newWords, tok)
+		} else {
+			c.Added = append(c.Added, tok.Value)
+		}
+	}
+	if len(oldWords) == len(newWords) {
+		for k := range oldWords {
+			if oldWords[k] != newWords[k] {
+				c.Before = append(c.Before, oldWords[k].Value)
+				c.After = append(c.After, newWords[k].Value)
+			}
+		}
+	} else {
+		for _, tok := range oldWords {
+			c.Removed = append(c.Removed, tok.Value)
+		}
+		for _, tok := range newWords {
+			c.Added = append(c.Added, tok.Value)
+		}
+	}
+
+	switch {
+	case c.Marker != "":
+		c.Kind = KindMarker
+	case len(c.Before) > 0:
+		c.Kind = KindReplace
+	case len(c.Removed) > 0 && len(c.Added) > 0:
+		c.Kind = KindReplace
+	case len(c.Removed) > 0:
+		c.Kind = KindDelete
+	default:
+		c.Kind = KindInsert
+	}
+
+	c.Line, c.Col = Position(before, s.b0)
+	c.EndLine, c.EndCol = Position(before, s.b1)
+	c.Original = join(before[s.b0:s.b1])
+	c.New = join(after[s.a0:s.a1])
+	return c
+}
+
+func join(tokens []tokenizer.Token) string {
+	var b strings.Builder
+	for _, tok := range tokens {
+		b.WriteString(tok.Value)
+	}
+	return b.String()
+}
+
+// Position returns the line and column, from 1, at which tokens[at] starts.
+// Columns count runes.
+func Position(tokens []tokenizer.Token, at int) (int, int) {
+	line, col := 1, 1
+	for _, tok := range tokens[:min(at, len(tokens))] {
+		line, col = advance(line, col, tok.Value)
+	}
+	return line, col
+}
+
+// advance returns the line and column after s, which starts at line and
+// col.
+func advance(line, col int, s string) (int, int) {
+	for _, r := range s {
+		if r == '\n' {
+			line, col = line+1, 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// markerVerbs describe what each marker does to the words it covers.
+var markerVerbs = map[string]string{
+	"up":  "uppercased",
+	"low": "lowercased",
+	"cap": "capitalized",
+	"hex": "converted from hexadecimal",
+	"bin": "converted from binary",
+}
+
+// String describes the change, e.g.
+// "CaseProcessor: (cap, 3) at 1:42 capitalized 'hero' 'named' 'link'".
+func (c Change) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: ", c.Stage)
+	if c.Marker != "" {
+		fmt.Fprintf(&b, "%s ", c.Marker)
+	}
+	fmt.Fprintf(&b, "at %d:%d ", c.Line, c.Col)
+
+	switch {
+	case c.Marker != "" && len(c.Before) > 0:
+		fmt.Fprintf(&b, "%s %s", markerVerb(c.Marker), quoteAll(c.Before))
+		if !sameLetters(c.Before, c.After) {
+			fmt.Fprintf(&b, " to %s", quoteAll(c.After))
+		}
+	case c.Marker != "" && len(c.Added) > 0:
+		fmt.Fprintf(&b, "expanded to '%s'", strings.Join(strings.Fields(c.New), " "))
+	case c.Marker != "":
+		b.WriteString("removed the marker without changing any words")
+	case len(c.Before) > 0:
+		fmt.Fprintf(&b, "replaced %s with %s", quoteAll(c.Before), quoteAll(c.After))
+	default:
+		b.WriteString(describeEdit(c.Removed, c.Added))
+	}
+	return b.String()
+}
+
+// describeEdit describes tokens that were removed and added.
+func describeEdit(removed, added []string) string {
+	switch {
+	case len(removed) == 0 && len(added) == 0:
+		return "made no visible change"
+	case allBlank(removed) && allBlank(added) && len(removed) > 0 && len(added) > 0:
+		return "adjusted spacing"
+	case len(added) == 0 && allBlank(removed):
+		return "removed space"
+	case len(removed) == 0 && allBlank(added):
+		return "inserted space"
+	case len(added) == 0:
+		return "removed " + quoteAll(removed)
+	case len(removed) == 0:
+		return "inserted " + quoteAll(added)
+	default:
+		return fmt.Sprintf("replaced %s with %s", quoteAll(removed), quoteAll(added))
+	}
+}
+
+// markerVerb returns the verb for a marker such as "(cap, 3)".
+func markerVerb(marker string) string {
+	name := strings.Trim(strings.ToLower(marker), "() ")
+	if k := strings.IndexByte(name, ','); k >= 0 {
+		name = strings.TrimSpace(name[:k])
+	}
+	if verb, ok := markerVerbs[name]; ok {
+		return verb
+	}
+	return "changed"
+}
+
+// sameLetters reports whether the words only changed case, in which case
+// the verb already says what happened.
+func sameLetters(before, after []string) bool {
+	for k := range before {
+		if !strings.EqualFold(before[k], after[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func allBlank(values []string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteAll renders values as 'a' 'b' 'c'.
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for k, v := range values {
+		quoted[k] = "'" + v + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,185 @@
+package explain
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/pkg/processors"
+	"go-reloaded/pkg/tokenizer"
+)
+
+func explainText(t *testing.T, text string) []string {
+	t.Helper()
+	var rec Recorder
+	pl, err := pipeline.NewWithOptions(pipeline.Options{Trace: rec.Trace})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := pl.Process(context.Background(), tokenizer.Tokenize(text)); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var got []string
+	for _, c := range rec.Changes {
+		got = append(got, c.String())
+	}
+	return got
+}
+
+func TestRecorder(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "marker with count",
+			input: "there was a hero named link (cap, 3)",
+			want: []string{
+				"CaseProcessor: (cap, 3) at 1:13 capitalized 'hero' 'named' 'link'",
+				"ArticleProcessor: at 1:11 replaced 'a' with 'an'",
+			},
+		},
+		{
+			name:  "conversion and article",
+			input: "a 1E (hex) apple\nnext , line",
+			want: []string{
+				"HexBinProcessor: (hex) at 1:3 converted from hexadecimal '1E' to '30'",
+				"PunctuationProcessor: at 1:11 removed space",
+				"PunctuationProcessor: at 2:5 removed space",
+			},
+		},
+		{
+			name:  "positions in the input after a removed marker",
+			input: "it was an apple (up) here .",
+			want: []string{
+				"CaseProcessor: (up) at 1:11 uppercased 'apple'",
+				"PunctuationProcessor: at 1:21 removed space",
+				"PunctuationProcessor: at 1:26 removed space",
+			},
+		},
+		{
+			name:  "article",
+			input: "a apple",
+			want:  []string{"ArticleProcessor: at 1:1 replaced 'a' with 'an'"},
+		},
+		{
+			name:  "no changes",
+			input: "all good here.",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := explainText(t, tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes:\n got  %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorder_SeveralTexts(t *testing.T) {
+	var rec Recorder
+	pl, err := pipeline.NewWithOptions(pipeline.Options{Trace: rec.Trace})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	// The second text is what the first became, so only the stages tell
+	// them apart
+	for _, text := range []string{"a apple ,ok", "an apple, ok", "x ,y"} {
+		if _, err := pl.Format(context.Background(), text); err != nil {
+			t.Fatalf("Format() error = %v", err)
+		}
+	}
+
+	var got []string
+	for _, c := range rec.Changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"ArticleProcessor: at 1:1 replaced 'a' with 'an'",
+		"PunctuationProcessor: at 1:8 removed space",
+		"PunctuationProcessor: at 1:10 inserted space",
+		"PunctuationProcessor: at 1:2 removed space",
+		"PunctuationProcessor: at 1:4 inserted space",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes:\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestRecorder_Paragraphs(t *testing.T) {
+	var rec Recorder
+	pl, err := pipeline.NewWithOptions(pipeline.Options{Trace: rec.Trace})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := pl.Format(context.Background(), "a apple\n\nwait ,what (up)"); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	var got []string
+	for _, c := range rec.Changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"CaseProcessor: (up) at 3:7 uppercased 'what'",
+		"ArticleProcessor: at 1:1 replaced 'a' with 'an'",
+		"PunctuationProcessor: at 3:5 removed space",
+		"PunctuationProcessor: at 3:7 inserted space",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes:\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := tokenizer.Tokenize("one two three four five six")
+	after := tokenizer.Tokenize("one TWO three four five six seven")
+
+	got := Diff("Test", before, after)
+	want := []Change{
+		{Stage: "Test", Kind: KindReplace, Before: []string{"two"}, After: []string{"TWO"},
+			Original: "two", New: "TWO", Line: 1, Col: 5, EndLine: 1, EndCol: 8},
+		{Stage: "Test", Kind: KindInsert, Added: []string{" ", "seven"},
+			New: " seven", Line: 1, Col: 28, EndLine: 1, EndCol: 28},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff():\n got  %+v\n want %+v", got, want)
+	}
+}
+
+func TestRecorder_Macros(t *testing.T) {
+	var rec Recorder
+	pl, err := pipeline.NewWithOptions(pipeline.Options{
+		Trace: rec.Trace,
+		Macros: map[string]processors.Macro{
+			"tm":  {Text: "™"},
+			"cut": {Pattern: regexp.MustCompile(`^(\w{3})\w+$`), Replace: "$1."},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := pl.Format(context.Background(), "Acme(tm) is an abbreviation (cut) here"); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	var got []string
+	for _, c := range rec.Changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"MacroProcessor: (tm) at 1:5 expanded to '™'",
+		"MacroProcessor: (cut) at 1:16 changed 'abbreviation' to 'abb.'",
+		"PunctuationProcessor: at 1:34 removed space",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes:\n got  %q\n want %q", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package formats
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSV formats the fields of the selected columns of a CSV file, or of every
+// column when columns is empty. Columns are numbered from 1. The header row
+// is left alone unless noHeader is set. Fields that are not changed keep
+// their exact bytes, and changed fields keep their original quoting.
+func CSV(src string, format TextFunc, columns []int, noHeader bool) (string, error) {
+	r := csv.NewReader(strings.NewReader(src))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	lineStarts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	selected := func(col int) bool {
+		if len(columns) == 0 {
+			return true
+		}
+		for _, c := range columns {
+			if c == col+1 {
+				return true
+			}
+		}
+		return false
+	}
+
+	var edits []edit
+	for row := 0; ; row++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parsing CSV: %w", err)
+		}
+		if row == 0 && !noHeader {
+			continue
+		}
+
+		for col, value := range record {
+			if !selected(col) {
+				continue
+			}
+			formatted := formatLines(value, format)
+			if formatted == value {
+				continue
+			}
+			line, column := r.FieldPos(col)
+			start := lineStarts[line-1] + column - 1
+			end, quoted := csvFieldEnd(src, start)
+			edits = append(edits, edit{start, end, csvQuote(formatted, quoted)})
+		}
+	}
+
+	return applyEdits(src, edits), nil
+}
+
+// csvFieldEnd returns the end of the raw field starting at start and whether
+// it is quoted.
+func csvFieldEnd(src string, start int) (int, bool) {
+	if start < len(src) && src[start] == '"' {
+		for i := start + 1; i < len(src); i++ {
+			if src[i] != '"' {
+				continue
+			}
+			if i+1 < len(src) && src[i+1] == '"' {
+				i++ // an escaped quote
+				continue
+			}
+			return i + 1, true
+		}
+		return len(src), true
+	}
+	end := start
+	for end < len(src) && src[end] != ',' && src[end] != '\n' && src[end] != '\r' {
+		end++
+	}
+	return end, false
+}
+
+// csvQuote encodes a field, quoting it when it was quoted before or when
+// its new value needs quotes.
+func csvQuote(value string, quoted bool) string {
+	if !quoted && !strings.ContainsAny(value, ",\"\r\n") {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+}
@@ -0,0 +1,55 @@
+package formats
+
+import "testing"
+
+func TestCSV(t *testing.T) {
+	format := defaultFormat(t)
+	input := "id,a item ,description\r\n" +
+		"1,a apple ,\"a apple ,red\"\r\n" +
+		"2,x ,y,plain ,text\r\n" +
+		"3,keep,\"multi\nline ,text \"\"quoted\"\" \"\r\n"
+
+	tests := []struct {
+		name     string
+		columns  []int
+		noHeader bool
+		want     string
+	}{
+		{
+			name:    "selected column",
+			columns: []int{3},
+			want: "id,a item ,description\r\n" +
+				"1,a apple ,\"an apple, red\"\r\n" +
+				"2,x ,y,plain ,text\r\n" +
+				"3,keep,\"multi\nline, text \"\"quoted\"\" \"\r\n",
+		},
+		{
+			name: "all columns",
+			want: "id,a item ,description\r\n" +
+				"1,an apple ,\"an apple, red\"\r\n" +
+				"2,x ,y,plain ,text\r\n" +
+				"3,keep,\"multi\nline, text \"\"quoted\"\" \"\r\n",
+		},
+		{
+			name:     "no header",
+			columns:  []int{2},
+			noHeader: true,
+			want: "id,an item ,description\r\n" +
+				"1,an apple ,\"a apple ,red\"\r\n" +
+				"2,x ,y,plain ,text\r\n" +
+				"3,keep,\"multi\nline ,text \"\"quoted\"\" \"\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CSV(input, format, tt.columns, tt.noHeader)
+			if err != nil {
+				t.Fatalf("CSV() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CSV():\n got  %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Package formats rewrites structured documents by running only their prose
+// through the formatting pipeline and copying everything else verbatim.
+package formats
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TextFunc formats one run of prose.
+type TextFunc func(string) string
+
+// Handler rewrites a whole document, passing its prose runs to format.
+type Handler func(src string, format TextFunc) (string, error)
+
+// Options tune the handlers that have settings of their own.
+type Options struct {
+	// GoStrings also formats the string literals passed to fmt.Errorf,
+	// errors.New and log calls in Go source
+	GoStrings bool
+	// Columns lists the CSV columns to format, numbered from 1; empty means
+	// every column
+	Columns []int
+	// NoHeader formats the first CSV record too instead of keeping it as
+	// the header row
+	NoHeader bool
+}
+
+// handlers build the handler for each format from the options.
+var handlers = map[string]func(Options) Handler{
+	"text":     fixed(Text),
+	"markdown": fixed(Markdown),
+	"html":     fixed(HTML),
+	"srt":      fixed(SRT),
+	"vtt":      fixed(VTT),
+	"po":       fixed(PO),
+	"json":     fixed(JSON),
+	"csv": func(opts Options) Handler {
+		return func(src string, format TextFunc) (string, error) {
+			return CSV(src, format, opts.Columns, opts.NoHeader)
+		}
+	},
+	"go": func(opts Options) Handler {
+		return func(src string, format TextFunc) (string, error) {
+			return Go(src, format, opts.GoStrings)
+		}
+	},
+}
+
+// fixed wraps a handler that takes no options.
+func fixed(h Handler) func(Options) Handler {
+	return func(Options) Handler { return h }
+}
+
+// extensions maps file extensions to the format used when none is given.
+var extensions = map[string]string{
+	".md":       "markdown",
+	".markdown": "markdown",
+	".html":     "html",
+	".htm":      "html",
+	".srt":      "srt",
+	".vtt":      "vtt",
+	".go":       "go",
+	".po":       "po",
+	".pot":      "po",
+	".json":     "json",
+	".csv":      "csv",
+}
+
+// Lookup returns the handler registered under name, configured by opts.
+func Lookup(name string, opts Options) (Handler, bool) {
+	build, ok := handlers[strings.ToLower(name)]
+	if !ok {
+		return nil, false
+	}
+	return build(opts), true
+}
+
+// Detect picks a format from the extension of path, falling back to plain
+// text.
+func Detect(path string) string {
+	if name, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return name
+	}
+	return "text"
+}
+
+// Names lists the registered formats in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Text treats the whole document as a single run of prose.
+func Text(src string, format TextFunc) (string, error) {
+	return format(src), nil
+}
+
+// formatTrimmed formats s but keeps its leading and trailing whitespace
+// untouched, so layout around a run survives.
+func formatTrimmed(s string, format TextFunc) string {
+	body := strings.TrimSpace(s)
+	if body == "" {
+		return s
+	}
+	start := strings.Index(s, body)
+	// A dropped trailing marker leaves a space behind; the run's own
+	// trailing whitespace is put back instead
+	return s[:start] + strings.TrimRight(format(body), " \t") + s[start+len(body):]
+}
+
+// formatText formats a run of prose taken from inside markup. Leading and
+// trailing whitespace that holds a line break is layout and stays as is;
+// other surrounding spaces are left to the pipeline so " , ok" after a tag
+// still loses its space.
+func formatText(s string, format TextFunc) string {
+	if strings.TrimSpace(s) == "" {
+		return s
+	}
+	body := strings.TrimLeft(s, " \t\r\n")
+	lead := s[:len(s)-len(body)]
+	if !strings.Contains(lead, "\n") {
+		lead, body = "", s
+	}
+	trimmed := strings.TrimRight(body, " \t\r\n")
+	trail := body[len(trimmed):]
+	if !strings.Contains(trail, "\n") {
+		trail, trimmed = "", body
+	}
+	return lead + format(trimmed) + trail
+}
+
+// formatPieces formats texts as one run, joined by sep, and returns each
+// text formatted. sep is tokenizer.Markup for the prose that inline markup
+// splits a run into, or tokenizer.SoftBreak for the lines of a wrapped
+// paragraph; either way sentences, markers and articles reach across it.
+// If the pipeline drops a separator, each text is formatted on its own
+// with each instead.
+func formatPieces(texts []string, sep rune, format TextFunc, each func(string) string) []string {
+	if len(texts) > 1 {
+		sep := string(sep)
+		joined := strings.Join(texts, sep)
+		if strings.Count(joined, sep) == len(texts)-1 {
+			if out := strings.Split(format(joined), sep); len(out) == len(texts) {
+				return out
+			}
+		}
+	}
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		out[i] = each(text)
+	}
+	return out
+}
+
+// formatLines formats each line of a message on its own, so no stray
+// space ends up before an embedded line break.
+func formatLines(s string, format TextFunc) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = formatTrimmed(line, format)
+	}
+	return strings.Join(lines, "\n")
+}
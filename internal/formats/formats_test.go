@@ -0,0 +1,22 @@
+package formats
+
+import (
+	"context"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+)
+
+// defaultFormat returns a TextFunc that formats with the default pipeline
+// and fails the test if it errs.
+func defaultFormat(t *testing.T) TextFunc {
+	pl := pipeline.New()
+	return func(text string) string {
+		t.Helper()
+		out, err := pl.Format(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", text, err)
+		}
+		return out
+	}
+}
@@ -0,0 +1,280 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// goDirective matches comments read by tools rather than people, such as
+// //go:generate, //line, //export and //nolint:errcheck.
+var goDirective = regexp.MustCompile(`^//(?:line |extern |export |[a-z0-9]+:[a-z0-9]|\s*\+build)`)
+
+// goMessageCalls lists the calls whose string arguments are messages for
+// people, by package and function name. Print and Sprintf style calls are
+// left out: the text they build is often read by programs.
+var goMessageCalls = map[string]map[string]bool{
+	"fmt":    {"Errorf": true},
+	"errors": {"New": true},
+	"log": {
+		"Print": true, "Printf": true, "Println": true,
+		"Fatal": true, "Fatalf": true, "Fatalln": true,
+		"Panic": true, "Panicf": true, "Panicln": true,
+	},
+}
+
+// edit replaces src[start:end] with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// Go formats the comments of a Go source file and, when withStrings is set,
+// the string literals passed to fmt.Errorf, errors.New and log calls. Directives, cgo
+// preambles and code blocks inside doc comments are left alone. The code
+// itself is never touched; a file that was gofmt-clean is re-gofmt'd so
+// trailing comments stay aligned.
+func Go(src string, format TextFunc, withStrings bool) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return "", fmt.Errorf("parsing Go source: %w", err)
+	}
+
+	skip := cgoPreambles(file)
+	var edits []edit
+	for _, group := range file.Comments {
+		if !skip[group] {
+			edits = append(edits, formatCommentGroup(fset, group, format)...)
+		}
+	}
+
+	if withStrings {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || !isMessageCall(call) {
+				return true
+			}
+			for _, arg := range call.Args {
+				lit, ok := arg.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				if text, ok := formatGoString(lit.Value, format); ok {
+					start := fset.Position(lit.Pos()).Offset
+					edits = append(edits, edit{start, start + len(lit.Value), text})
+				}
+			}
+			return true
+		})
+	}
+
+	if len(edits) == 0 {
+		return src, nil
+	}
+	out := applyEdits(src, edits)
+
+	if clean, err := gofmt.Source([]byte(src)); err == nil && bytes.Equal(clean, []byte(src)) {
+		formatted, err := gofmt.Source([]byte(out))
+		if err != nil {
+			return "", fmt.Errorf("reformatting Go source: %w", err)
+		}
+		out = string(formatted)
+	}
+	return out, nil
+}
+
+// cgoPreambles returns the comment groups that hold C code for import "C".
+func cgoPreambles(file *ast.File) map[*ast.CommentGroup]bool {
+	skip := make(map[*ast.CommentGroup]bool)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if imp := spec.(*ast.ImportSpec); imp.Path.Value == `"C"` {
+				skip[gen.Doc] = true
+				skip[imp.Doc] = true
+			}
+		}
+	}
+	return skip
+}
+
+// commentLine is one line of a comment: its text and the decoration kept
+// on either side of it.
+type commentLine struct {
+	prefix, text, suffix string
+	prose                bool // whether text is formatted
+}
+
+func (l commentLine) String() string {
+	return l.prefix + l.text + l.suffix
+}
+
+// splitCommentLine returns the line prefix+body, whose body is prose unless
+// it is blank.
+func splitCommentLine(prefix, body string) commentLine {
+	text := strings.TrimSpace(body)
+	if text == "" {
+		return commentLine{prefix: prefix + body}
+	}
+	start := strings.Index(body, text)
+	return commentLine{prefix + body[:start], text, body[start+len(text):], true}
+}
+
+// formatCommentLines formats the text of lines. Consecutive lines of prose
+// are formatted as one run, so sentences, markers and articles reach across
+// them, and each line keeps its own text.
+func formatCommentLines(lines []commentLine, format TextFunc) {
+	for i := 0; i < len(lines); i++ {
+		var texts []string
+		for j := i; j < len(lines) && lines[j].prose; j++ {
+			texts = append(texts, lines[j].text)
+		}
+		// Spacing after punctuation may land before a line break, and a
+		// dropped marker leaves a space; the line's own suffix is put back
+		for k, text := range formatPieces(texts, tokenizer.SoftBreak, format, format) {
+			lines[i+k].text = strings.TrimRight(text, " \t")
+		}
+		i += len(texts)
+	}
+}
+
+// formatCommentGroup returns the edits that format the comments of group.
+// Its consecutive // comments are formatted as the lines of one text.
+func formatCommentGroup(fset *token.FileSet, group *ast.CommentGroup, format TextFunc) []edit {
+	var edits []edit
+	replace := func(c *ast.Comment, text string) {
+		if text != c.Text {
+			start := fset.Position(c.Pos()).Offset
+			edits = append(edits, edit{start, start + len(c.Text), text})
+		}
+	}
+
+	var lines []commentLine
+	var comments []*ast.Comment // the // comments lines holds
+	flush := func() {
+		formatCommentLines(lines, format)
+		for i, c := range comments {
+			replace(c, lines[i].String())
+		}
+		lines, comments = lines[:0], comments[:0]
+	}
+	for _, c := range group.List {
+		if !strings.HasPrefix(c.Text, "//") {
+			flush()
+			replace(c, formatBlockComment(c.Text, format))
+			continue
+		}
+		comments = append(comments, c)
+		body := c.Text[2:]
+		// Indented lines are code blocks in doc comments
+		if goDirective.MatchString(c.Text) || strings.HasPrefix(body, "\t") || strings.HasPrefix(body, "  ") {
+			lines = append(lines, commentLine{prefix: c.Text})
+			continue
+		}
+		lines = append(lines, splitCommentLine("//", body))
+	}
+	flush()
+	return edits
+}
+
+// formatBlockComment formats a /* */ comment, whose consecutive lines of
+// prose are formatted as one text.
+func formatBlockComment(text string, format TextFunc) string {
+	body := text[2 : len(text)-2]
+	var lines []commentLine
+	for i, line := range strings.Split(body, "\n") {
+		// Keep the leading " * " decoration of block comments
+		trimmed := strings.TrimLeft(line, " \t")
+		lead := line[:len(line)-len(trimmed)]
+		if strings.HasPrefix(trimmed, "*") {
+			lead += "*"
+			trimmed = trimmed[1:]
+		}
+		if i > 0 && (strings.HasPrefix(trimmed, "\t") || strings.HasPrefix(trimmed, "  ")) {
+			lines = append(lines, commentLine{prefix: line})
+			continue
+		}
+		lines = append(lines, splitCommentLine(lead, trimmed))
+	}
+	formatCommentLines(lines, format)
+
+	var out strings.Builder
+	out.WriteString("/*")
+	for i, l := range lines {
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+		out.WriteString(l.String())
+	}
+	out.WriteString("*/")
+	return out.String()
+}
+
+// isMessageCall reports whether call is one of goMessageCalls.
+func isMessageCall(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && goMessageCalls[pkg.Name][sel.Sel.Name]
+}
+
+// formatGoString formats the value of a string literal and quotes it again
+// in its original style. It reports false when nothing changed, or when the
+// value does not read as prose, as "%s:%d" and "key=%q,value=%v" do not.
+func formatGoString(lit string, format TextFunc) (string, bool) {
+	value, err := strconv.Unquote(lit)
+	if err != nil || !isProse(value) {
+		return "", false
+	}
+	formatted := formatTrimmed(value, format)
+	if formatted == value {
+		return "", false
+	}
+	if strings.HasPrefix(lit, "`") && !strings.Contains(formatted, "`") {
+		return "`" + formatted + "`", true
+	}
+	return strconv.Quote(formatted), true
+}
+
+// isProse reports whether s holds a space and a word besides placeholders
+// and punctuation. Anything else is more likely a format a program reads.
+func isProse(s string) bool {
+	if !strings.ContainsAny(s, " \t\n") {
+		return false
+	}
+	for _, tok := range tokenizer.Tokenize(s) {
+		if tok.Type == tokenizer.Word {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEdits splices non-overlapping edits into src.
+func applyEdits(src string, edits []edit) string {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out strings.Builder
+	last := 0
+	for _, e := range edits {
+		out.WriteString(src[last:e.start])
+		out.WriteString(e.text)
+		last = e.end
+	}
+	out.WriteString(src[last:])
+	return out.String()
+}
@@ -0,0 +1,114 @@
+package formats
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+)
+
+const goInput = `// Package demo is a example ,with comments .
+package demo
+
+/*
+#include <stdio.h>
+int a , b;
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+//go:generate stringer -type=Kind,Mode
+
+// Run does it (up) .
+//
+//	code , block
+func Run(a, b int) error {
+	x := a + b // sum ,really
+	if x > 0 {
+		fmt.Println("a apple ,ok")
+		return fmt.Errorf("a apple ,ok: %w", errors.New("%s:%d"))
+	}
+	addr := fmt.Sprintf("%s:%d", "localhost", 80)
+	log.Printf("key=%q,value=%v", addr, x)
+	label := "a apple ,ok"
+	_ = label
+	return errors.New(` + "`a error ,here`" + `)
+}
+`
+
+func TestGo_Comments(t *testing.T) {
+	got, err := Go(goInput, defaultFormat(t), false)
+	if err != nil {
+		t.Fatalf("Go() error = %v", err)
+	}
+
+	want := strings.NewReplacer(
+		"is a example ,with comments .", "is an example, with comments.",
+		"does it (up) .", "does IT.",
+		"// sum ,really", "// sum, really",
+	).Replace(goInput)
+	if got != want {
+		t.Errorf("Go():\n got  %s\n want %s", got, want)
+	}
+}
+
+func TestGo_Strings(t *testing.T) {
+	got, err := Go(goInput, defaultFormat(t), true)
+	if err != nil {
+		t.Fatalf("Go() error = %v", err)
+	}
+
+	for _, want := range []string{
+		`fmt.Println("a apple ,ok")`,
+		`fmt.Errorf("an apple, ok: %w", errors.New("%s:%d"))`,
+		`fmt.Sprintf("%s:%d", "localhost", 80)`,
+		`log.Printf("key=%q,value=%v", addr, x)`,
+		`label := "a apple ,ok"`,
+		"errors.New(`an error, here`)",
+		"int a , b;",
+		"//go:generate stringer -type=Kind,Mode",
+		"//\tcode , block",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Go() output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestGo_CommentGroups(t *testing.T) {
+	pl, err := pipeline.NewWithOptions(pipeline.Options{SentenceCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := func(text string) string {
+		out, err := pl.Format(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", text, err)
+		}
+		return out
+	}
+
+	// The lines of a comment are one text: sentences, markers and articles
+	// reach across them, and a blank comment line or code block ends it
+	input := "package demo\n\n// Foo returns the value\n// of a item (up).\n//\n// the end (up, 2)\n//\n//\tcode , block\nfunc Foo() {}\n\n/*\n * it was a\n * apple ,really\n */\nvar x int\n"
+	want := "package demo\n\n// Foo returns the value\n// of an ITEM.\n//\n// THE END\n//\n//\tcode , block\nfunc Foo() {}\n\n/*\n * It was an\n * apple, really\n */\nvar x int\n"
+	got, err := Go(input, format, false)
+	if err != nil {
+		t.Fatalf("Go() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("Go():\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestGo_ParseError(t *testing.T) {
+	if _, err := Go("package demo\nfunc {", defaultFormat(t), false); err == nil {
+		t.Error("expected error for invalid Go source")
+	}
+}
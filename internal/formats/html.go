@@ -0,0 +1,285 @@
+package formats
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// rawElements hold content that must never be reformatted.
+var rawElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"pre":      true,
+	"code":     true,
+	"textarea": true,
+}
+
+// inlineElements are the elements that sit inside a run of prose rather
+// than end it.
+var inlineElements = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "br": true,
+	"cite": true, "data": true, "del": true, "dfn": true, "em": true,
+	"font": true, "i": true, "img": true, "ins": true, "kbd": true,
+	"label": true, "mark": true, "q": true, "s": true, "samp": true,
+	"small": true, "span": true, "strong": true, "sub": true, "sup": true,
+	"time": true, "tt": true, "u": true, "var": true, "wbr": true,
+}
+
+// HTML formats the text nodes of an HTML document or fragment. Tags,
+// attributes, comments, doctypes and the contents of script, style, pre,
+// code and textarea elements are copied through byte for byte. The text
+// nodes between two block boundaries, with only inline tags and comments
+// among them, are formatted as one run.
+func HTML(src string, format TextFunc) (string, error) {
+	var out strings.Builder
+	out.Grow(len(src))
+
+	// texts and literals hold the run being read: the text nodes and the
+	// inline markup after each of them
+	var texts, literals []string
+	textStart := 0
+	flushRun := func(end int) {
+		texts = append(texts, src[textStart:end])
+		out.WriteString(formatHTMLRun(texts, literals, format))
+		texts, literals = texts[:0], literals[:0]
+	}
+
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			i++
+			continue
+		}
+		end, name := scanMarkup(src, i)
+		if end == i {
+			// A bare '<' is text
+			i++
+			continue
+		}
+		if rawElements[name] && !strings.HasSuffix(src[i:end], "/>") {
+			end = skipRawElement(src, end, name)
+		}
+		if markup := src[i:end]; isInlineMarkup(markup) {
+			texts = append(texts, src[textStart:i])
+			literals = append(literals, markup)
+		} else {
+			flushRun(i)
+			out.WriteString(markup)
+		}
+		i, textStart = end, end
+	}
+	flushRun(len(src))
+
+	return out.String(), nil
+}
+
+// isInlineMarkup reports whether markup, a tag, comment or whole raw
+// element, sits inside a run of prose.
+func isInlineMarkup(markup string) bool {
+	if strings.HasPrefix(markup, "<!--") {
+		return true
+	}
+	name := strings.TrimPrefix(markup[1:], "/")
+	end := strings.IndexFunc(name, func(r rune) bool {
+		return !(r < utf8.RuneSelf && isASCIILetter(byte(r)) || r >= '0' && r <= '9')
+	})
+	if end >= 0 {
+		name = name[:end]
+	}
+	return inlineElements[strings.ToLower(name)]
+}
+
+// scanMarkup returns the end of the markup starting at src[i] == '<' and,
+// for an opening tag, its lower-cased element name. It returns i when the
+// '<' does not start any markup.
+func scanMarkup(src string, i int) (int, string) {
+	rest := src[i:]
+	switch {
+	case strings.HasPrefix(rest, "<!--"):
+		return closeAfter(src, i+4, "-->"), ""
+	case strings.HasPrefix(rest, "<![CDATA["):
+		return closeAfter(src, i+9, "]]>"), ""
+	case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+		return closeAfter(src, i+2, ">"), ""
+	}
+
+	j := i + 1
+	closing := j < len(src) && src[j] == '/'
+	if closing {
+		j++
+	}
+	if j >= len(src) || !isASCIILetter(src[j]) {
+		return i, ""
+	}
+	nameStart := j
+	for j < len(src) && (isASCIILetter(src[j]) || src[j] >= '0' && src[j] <= '9' || src[j] == '-' || src[j] == ':') {
+		j++
+	}
+	name := strings.ToLower(src[nameStart:j])
+
+	// Find the closing '>', skipping over quoted attribute values
+	for quote := byte(0); j < len(src); j++ {
+		switch c := src[j]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			if closing {
+				return j + 1, ""
+			}
+			return j + 1, name
+		}
+	}
+	// An unterminated tag is treated as text
+	return i, ""
+}
+
+// skipRawElement returns the index just past the end tag of the raw element
+// whose content starts at i, or the end of src if it is never closed.
+func skipRawElement(src string, i int, name string) int {
+	lower := strings.ToLower(src[i:])
+	for off := 0; ; {
+		k := strings.Index(lower[off:], "</"+name)
+		if k < 0 {
+			return len(src)
+		}
+		k += off
+		next := k + 2 + len(name)
+		if next < len(lower) && (lower[next] == '>' || lower[next] == ' ' || lower[next] == '\t' || lower[next] == '\n' || lower[next] == '\r') {
+			return closeAfter(src, i+next, ">")
+		}
+		off = next
+	}
+}
+
+// closeAfter returns the index just past the first delim at or after i, or
+// the end of src if delim never appears.
+func closeAfter(src string, i int, delim string) int {
+	if k := strings.Index(src[i:], delim); k >= 0 {
+		return i + k + len(delim)
+	}
+	return len(src)
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// formatHTMLRun formats texts, the text nodes of one run, and returns them
+// joined by literals, the inline markup between them. Entities are decoded
+// before formatting and a node keeps its original bytes when nothing in it
+// changed. Otherwise characters the node spelled as entities, such as
+// &copy; or &#8212;, are spelled that way again, so that only the text the
+// pipeline changed differs.
+func formatHTMLRun(texts, literals []string, format TextFunc) string {
+	decoded := make([]string, len(texts))
+	for i, raw := range texts {
+		decoded[i] = html.UnescapeString(raw)
+	}
+	each := func(s string) string { return formatText(s, format) }
+	formatted := formatPieces(decoded, tokenizer.Markup, each, each)
+
+	var out strings.Builder
+	for i, raw := range texts {
+		if formatted[i] == decoded[i] {
+			out.WriteString(raw)
+		} else {
+			out.WriteString(escapeText(formatted[i], entitySpellings(raw)))
+		}
+		if i < len(literals) {
+			out.WriteString(literals[i])
+		}
+	}
+	return out.String()
+}
+
+var htmlEntity = regexp.MustCompile(`&(?:#[0-9]+|#[xX][0-9A-Fa-f]+|[A-Za-z][A-Za-z0-9]*);?`)
+
+// entitySpellings maps each character that raw spells as an entity at
+// least once to how raw spells it each time in turn: as an entity, or as
+// "" where it is literal.
+func entitySpellings(raw string) map[rune][]string {
+	all := make(map[rune][]string)
+	hasEntity := make(map[rune]bool)
+	literal := func(text string) {
+		for _, r := range text {
+			all[r] = append(all[r], "")
+		}
+	}
+
+	last := 0
+	for _, loc := range htmlEntity.FindAllStringIndex(raw, -1) {
+		literal(raw[last:loc[0]])
+		last = loc[1]
+		m := raw[loc[0]:loc[1]]
+		d := html.UnescapeString(m)
+		if r, size := utf8.DecodeRuneInString(d); d != m && size == len(d) {
+			all[r] = append(all[r], m)
+			hasEntity[r] = true
+		} else {
+			literal(d)
+		}
+	}
+	literal(raw[last:])
+
+	spellings := make(map[rune][]string, len(hasEntity))
+	for r := range hasEntity {
+		spellings[r] = all[r]
+	}
+	return spellings
+}
+
+// escapeText encodes the characters that cannot appear literally in an
+// HTML text node, and those in spellings as spelled there: occurrence by
+// occurrence if s has as many of them as the source, else with the first
+// entity. No-break spaces are spelled out so they stay visible.
+func escapeText(s string, spellings map[rune][]string) string {
+	seen := make(map[rune]int)
+	for r := range spellings {
+		if strings.Count(s, string(r)) != len(spellings[r]) {
+			// The pipeline added or dropped some; spell them all alike
+			for _, e := range spellings[r] {
+				if e != "" {
+					spellings[r] = []string{e}
+					break
+				}
+			}
+			seen[r] = -1
+		}
+	}
+
+	var out strings.Builder
+	out.Grow(len(s))
+	for _, r := range s {
+		if seq, ok := spellings[r]; ok {
+			e := seq[0]
+			if n := seen[r]; n >= 0 {
+				e = seq[n]
+				seen[r]++
+			}
+			if e != "" {
+				out.WriteString(e)
+				continue
+			}
+		}
+		switch r {
+		case '&':
+			out.WriteString("&amp;")
+		case '<':
+			out.WriteString("&lt;")
+		case '>':
+			out.WriteString("&gt;")
+		case '\u00A0':
+			out.WriteString("&nbsp;")
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,111 @@
+package formats
+
+import (
+	"context"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+)
+
+func TestHTML(t *testing.T) {
+	format := defaultFormat(t)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "text nodes",
+			input: "<p class=\"intro\">it was a apple ,really .</p>",
+			want:  "<p class=\"intro\">it was an apple, really.</p>",
+		},
+		{
+			name:  "attributes untouched",
+			input: "<a href=\"/x?a=1&amp;b=2\" title=\"a , b > c\">a link ,here</a>",
+			want:  "<a href=\"/x?a=1&amp;b=2\" title=\"a , b > c\">a link, here</a>",
+		},
+		{
+			name:  "text after inline tag",
+			input: "<p>Hello <b>world</b> , ok</p>",
+			want:  "<p>Hello <b>world</b>, ok</p>",
+		},
+		{
+			name:  "articles and markers reach across inline tags",
+			input: "<p>it was a <em>apple</em> ,<b>really</b> (up) here</p><p>a</p><p>apple</p>",
+			want:  "<p>it was an <em>apple</em>, <b>REALLY</b> here</p><p>a</p><p>apple</p>",
+		},
+		{
+			name:  "raw elements skipped",
+			input: "<pre>a , b</pre><code>x ,y</code><script>if (a < b) { x , y }</script><STYLE>p , a {}</STYLE>z ,w",
+			want:  "<pre>a , b</pre><code>x ,y</code><script>if (a < b) { x , y }</script><STYLE>p , a {}</STYLE>z, w",
+		},
+		{
+			name:  "comments and doctype",
+			input: "<!DOCTYPE html><!-- a , b --><p>c ,d</p>",
+			want:  "<!DOCTYPE html><!-- a , b --><p>c, d</p>",
+		},
+		{
+			name:  "entities decoded and re-encoded",
+			input: "<p>Tom &amp; Jerry ,friends &lt;3</p>",
+			want:  "<p>Tom &amp; Jerry, friends &lt;3</p>",
+		},
+		{
+			name:  "unchanged node keeps its entities",
+			input: "<p>caf&eacute; &#233;&nbsp;ok</p>",
+			want:  "<p>caf&eacute; &#233;&nbsp;ok</p>",
+		},
+		{
+			name:  "no-break spaces spelled out",
+			input: "<p>café ,ok\u00A0here</p>",
+			want:  "<p>café, ok&nbsp;here</p>",
+		},
+		{
+			name:  "changed node keeps its entities",
+			input: "<p>&copy; 2024 acme ,inc &mdash; caf&eacute; &#8212; &#x2026; a idea &amp; more&hellip;</p>",
+			want:  "<p>&copy; 2024 acme, inc &mdash; caf&eacute; &#8212; &#x2026; an idea &amp; more&hellip;</p>",
+		},
+		{
+			name:  "layout whitespace kept",
+			input: "<ul>\n  <li>\n    one ,two\n  </li>\n</ul>\n",
+			want:  "<ul>\n  <li>\n    one, two\n  </li>\n</ul>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTML(tt.input, format)
+			if err != nil {
+				t.Fatalf("HTML() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("HTML():\n got  %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTML_InlineTags(t *testing.T) {
+	pl, err := pipeline.NewWithOptions(pipeline.Options{SentenceCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := func(text string) string {
+		out, err := pl.Format(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", text, err)
+		}
+		return out
+	}
+
+	// Inline tags sit inside a sentence; block elements start a new one
+	input := "<p>it was a <em>apple</em> and a <b>orange</b> here.</p><ul><li>one</li><li>two <i>three</i></li></ul>"
+	want := "<p>It was an <em>apple</em> and an <b>orange</b> here.</p><ul><li>One</li><li>Two <i>three</i></li></ul>"
+	got, err := HTML(input, format)
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("HTML():\n got  %q\n want %q", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonContainer tracks an open object or array while scanning.
+type jsonContainer struct {
+	object    bool
+	expectKey bool // the next string is an object key
+}
+
+// JSON formats the string values of a flat or nested JSON message file.
+// Keys, numbers, layout and member order are kept exactly; only the string
+// values that change are re-encoded.
+func JSON(src string, format TextFunc) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.UseNumber()
+
+	var stack []jsonContainer
+	var edits []edit
+
+	// gotValue records a finished value in the innermost container
+	gotValue := func() {
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].expectKey = true
+		}
+	}
+
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parsing JSON: %w", err)
+		}
+		end := int(dec.InputOffset())
+
+		switch v := tok.(type) {
+		case json.Delim:
+			switch v {
+			case '{':
+				stack = append(stack, jsonContainer{object: true, expectKey: true})
+			case '[':
+				stack = append(stack, jsonContainer{})
+			default:
+				stack = stack[:len(stack)-1]
+				gotValue()
+			}
+			continue
+
+		case string:
+			if n := len(stack); n > 0 && stack[n-1].expectKey {
+				stack[n-1].expectKey = false // a key; its value comes next
+				continue
+			}
+			if formatted := formatLines(v, format); formatted != v {
+				// The literal follows whatever separators were read since start
+				start += strings.IndexByte(src[start:end], '"')
+				edits = append(edits, edit{start, end, jsonQuote(formatted)})
+			}
+		}
+		gotValue()
+	}
+	if len(stack) > 0 {
+		return "", fmt.Errorf("parsing JSON: %w", io.ErrUnexpectedEOF)
+	}
+
+	return applyEdits(src, edits), nil
+}
+
+// jsonQuote encodes s as a JSON string, leaving <, > and & readable.
+func jsonQuote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // encoding a string cannot fail
+	return strings.TrimSuffix(buf.String(), "\n")
+}
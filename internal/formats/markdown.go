@@ -0,0 +1,346 @@
+package formats
+
+import (
+	"regexp"
+	"strings"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+var (
+	mdFence       = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	mdThematic    = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdSetext      = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	mdHTMLBlock   = regexp.MustCompile(`^ {0,3}<(?:[A-Za-z][A-Za-z0-9-]*[\s/>]|[A-Za-z][A-Za-z0-9-]*$|/[A-Za-z]|!--|!\[CDATA\[|![A-Z]|\?)`)
+	mdLinkDef     = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:`)
+	mdTableDelim  = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdIndented    = regexp.MustCompile(`^(?: {4}|\t)`)
+	mdListItem    = regexp.MustCompile(`^[ \t]*(?:>[ \t]?)*[ \t]*(?:[-*+]|\d{1,9}[.)])(?:[ \t]+|$)`)
+	mdPrefix      = regexp.MustCompile(`^[ \t]*(?:>[ \t]?)*(?:[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+(?:\[[ xX]\][ \t]+)?)?(?:#{1,6}(?:[ \t]+|$))?`)
+	mdClosingHash = regexp.MustCompile(`[ \t]+#+[ \t]*$`)
+	mdInlineTag   = regexp.MustCompile(`^<(?:[A-Za-z][A-Za-z0-9+.\-]*:[^\s<>]*|[^\s<>@]+@[^\s<>]+|/?[A-Za-z][^<>]*|!--.*?--)>`)
+)
+
+// Markdown formats the prose of a Markdown document. Code blocks, front
+// matter, HTML blocks, link destinations, code spans and block markers such
+// as headings, bullets and quotes are copied through byte for byte.
+func Markdown(src string, format TextFunc) (string, error) {
+	lines := strings.SplitAfter(src, "\n")
+	var out strings.Builder
+	out.Grow(len(src))
+
+	var (
+		fence    string // closing fence while inside a fenced code block
+		inHTML   bool
+		htmlEnd  string // what closes the HTML block being read; "" for a blank line
+		inList   bool
+		prevCode bool
+		prevText bool
+		para     []mdLine // the lines of the paragraph being read
+	)
+	prevBlank := true
+
+	// flush formats the pending paragraph and writes it out
+	flush := func() {
+		out.WriteString(formatParagraph(para, format))
+		para = para[:0]
+	}
+	// emit writes a line that is not part of a paragraph
+	emit := func(line string) {
+		flush()
+		out.WriteString(line)
+	}
+
+	start := frontMatterEnd(lines)
+	for _, line := range lines[:start] {
+		out.WriteString(line)
+	}
+
+	for _, line := range lines[start:] {
+		body := strings.TrimRight(line, "\r\n")
+		eol := line[len(body):]
+
+		switch {
+		case fence != "":
+			emit(line)
+			trimmed := strings.TrimSpace(body)
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+
+		case mdFence.MatchString(body):
+			marker := mdFence.FindStringSubmatch(body)[1]
+			fence = marker
+			emit(line)
+			prevBlank, prevCode, prevText = false, false, false
+			continue
+
+		case inHTML:
+			emit(line)
+			if htmlEnd != "" && strings.Contains(strings.ToLower(body), htmlEnd) {
+				inHTML = false
+			} else if htmlEnd == "" && strings.TrimSpace(body) == "" {
+				inHTML = false
+				prevBlank = true
+			}
+			continue
+
+		case strings.TrimSpace(body) == "":
+			emit(line)
+			prevBlank = true
+			prevText = false
+			continue
+
+		case mdIndented.MatchString(body) && (prevBlank || prevCode) && !inList:
+			emit(line)
+			prevBlank, prevCode = false, true
+			continue
+
+		case mdHTMLBlock.MatchString(body) && !prevText:
+			htmlEnd = htmlBlockEnd(body)
+			// The block may close on the line that opens it, after the '<'
+			// and the character that follows
+			opener := strings.IndexByte(body, '<') + 2
+			inHTML = htmlEnd == "" || !strings.Contains(strings.ToLower(body[min(opener, len(body)):]), htmlEnd)
+			emit(line)
+			prevBlank, prevCode = false, false
+			continue
+
+		case mdThematic.MatchString(body), mdSetext.MatchString(body), mdLinkDef.MatchString(body),
+			strings.Contains(body, "|") && mdTableDelim.MatchString(body):
+			emit(line)
+			prevBlank, prevCode, prevText = false, false, false
+			continue
+		}
+
+		if mdListItem.MatchString(body) {
+			inList = true
+		} else if prevBlank && !mdIndented.MatchString(body) {
+			inList = false
+		}
+		prevBlank, prevCode, prevText = false, false, true
+
+		if strings.HasPrefix(strings.TrimSpace(body), "|") {
+			emit(formatTableRow(body, format) + eol)
+			continue
+		}
+
+		prefix := mdPrefix.FindString(body)
+		content := body[len(prefix):]
+		suffix := content[len(strings.TrimRight(content, " \t")):]
+		content = content[:len(content)-len(suffix)]
+		if strings.Contains(prefix, "#") {
+			if loc := mdClosingHash.FindStringIndex(content); loc != nil {
+				suffix = content[loc[0]:] + suffix
+				content = content[:loc[0]]
+			}
+		}
+
+		// A heading or list item starts a paragraph of its own, and a
+		// heading ends it too
+		heading := strings.Contains(prefix, "#")
+		if heading || mdListItem.MatchString(body) {
+			flush()
+		}
+		para = append(para, mdLine{prefix, content, suffix, eol})
+		if heading {
+			flush()
+		}
+	}
+	flush()
+
+	return out.String(), nil
+}
+
+// mdLine is a line of a paragraph, split into its block markers, the text
+// to format and the layout after it.
+type mdLine struct {
+	prefix, content, suffix, eol string
+}
+
+// formatParagraph formats the lines of a paragraph as one run of text, so
+// that a sentence, a marker or an article can span a soft line break. The
+// lines are joined with tokenizer.SoftBreak, which the pipeline sees as a
+// space, and split at it again; each line keeps its markers and layout. If
+// the pipeline drops a break, say as a space before punctuation, the lines
+// are formatted one by one instead.
+func formatParagraph(para []mdLine, format TextFunc) string {
+	contents := make([]string, len(para))
+	for i, l := range para {
+		contents[i] = l.content
+	}
+	sep := string(tokenizer.SoftBreak)
+	joined := strings.Join(contents, sep)
+	formatted := strings.Split(formatInline(joined, format), sep)
+	if len(formatted) != len(para) || strings.Count(joined, sep) != len(para)-1 {
+		formatted = make([]string, len(para))
+		for i, c := range contents {
+			formatted[i] = formatInline(c, format)
+		}
+	}
+
+	var out strings.Builder
+	for i, l := range para {
+		out.WriteString(l.prefix)
+		// Spacing after punctuation may land before a line break, and a
+		// dropped marker leaves a space; the line's own suffix is put back
+		out.WriteString(strings.TrimRight(formatted[i], " \t"))
+		out.WriteString(l.suffix)
+		out.WriteString(l.eol)
+	}
+	return out.String()
+}
+
+// frontMatterEnd returns the number of leading lines taken by a YAML front
+// matter block, or 0 if the document has none.
+func frontMatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimRight(lines[0], "\r\n") != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		if l := strings.TrimRight(lines[i], "\r\n"); l == "---" || l == "..." {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// formatTableRow formats each cell of a table row, keeping the pipes and
+// the padding around cell contents.
+func formatTableRow(row string, format TextFunc) string {
+	var out strings.Builder
+	cellStart := 0
+	for i := 0; i <= len(row); i++ {
+		if i < len(row) && (row[i] != '|' || (i > 0 && row[i-1] == '\\')) {
+			continue
+		}
+		out.WriteString(formatTrimmed(row[cellStart:i], func(s string) string {
+			return formatInline(s, format)
+		}))
+		if i < len(row) {
+			out.WriteByte('|')
+		}
+		cellStart = i + 1
+	}
+	return out.String()
+}
+
+// htmlBlockEnd returns what closes the HTML block that line opens: the
+// end of a comment, processing instruction, declaration or CDATA section,
+// or the end tag of a pre, script, style or textarea element. It returns ""
+// for the other blocks, which a blank line closes.
+func htmlBlockEnd(line string) string {
+	tag := strings.ToLower(strings.TrimLeft(line, " "))
+	switch {
+	case strings.HasPrefix(tag, "<!--"):
+		return "-->"
+	case strings.HasPrefix(tag, "<?"):
+		return "?>"
+	case strings.HasPrefix(tag, "<![cdata["):
+		return "]]>"
+	case strings.HasPrefix(tag, "<!"):
+		return ">"
+	}
+	for _, name := range []string{"pre", "script", "style", "textarea"} {
+		if rest, ok := strings.CutPrefix(tag, "<"+name); ok && (rest == "" || strings.ContainsAny(rest[:1], " \t>")) {
+			return "</" + name + ">"
+		}
+	}
+	return ""
+}
+
+// formatInline formats inline text, copying inline HTML, autolinks, link
+// destinations and image markers verbatim. The text around them is
+// formatted as one run.
+func formatInline(s string, format TextFunc) string {
+	var texts, literals []string
+	textStart := 0
+
+	literal := func(start, end int) int {
+		texts = append(texts, s[textStart:start])
+		literals = append(literals, s[start:end])
+		textStart = end
+		return end
+	}
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i += 2
+
+		case s[i] == '`':
+			n := 0
+			for i+n < len(s) && s[i+n] == '`' {
+				n++
+			}
+			// The tokenizer already protects code spans; skip over them so
+			// their contents are not mistaken for links or tags
+			ticks := s[i : i+n]
+			if end := strings.Index(s[i+n:], ticks); end >= 0 {
+				i += n + end + n
+			} else {
+				i += n
+			}
+
+		case s[i] == '<':
+			if loc := mdInlineTag.FindStringIndex(s[i:]); loc != nil {
+				i = literal(i, i+loc[1])
+			} else {
+				i++
+			}
+
+		case strings.HasPrefix(s[i:], "!["):
+			i = literal(i, i+2)
+
+		case strings.HasPrefix(s[i:], "]("):
+			i = literal(i, closingParen(s, i+2))
+
+		case strings.HasPrefix(s[i:], "]["):
+			if end := strings.IndexByte(s[i+2:], ']'); end >= 0 {
+				i = literal(i, i+2+end+1)
+			} else {
+				i++
+			}
+
+		default:
+			i++
+		}
+	}
+	texts = append(texts, s[textStart:])
+
+	formatted := formatPieces(texts, tokenizer.Markup, format, func(text string) string {
+		if strings.TrimSpace(text) == "" {
+			return text
+		}
+		return format(text)
+	})
+	var out strings.Builder
+	for i, text := range formatted {
+		out.WriteString(text)
+		if i < len(literals) {
+			out.WriteString(literals[i])
+		}
+	}
+	return out.String()
+}
+
+// closingParen returns the index just past the parenthesis closing a link
+// destination that starts at i, allowing balanced parentheses inside.
+func closingParen(s string, i int) int {
+	depth := 1
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
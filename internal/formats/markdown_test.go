@@ -0,0 +1,185 @@
+package formats
+
+import (
+	"context"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+)
+
+func TestMarkdown(t *testing.T) {
+	format := defaultFormat(t)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "paragraph",
+			input: "it was a apple ,really .\n",
+			want:  "it was an apple, really.\n",
+		},
+		{
+			name:  "heading and closing hashes",
+			input: "## a idea ,here ##\n",
+			want:  "## an idea, here ##\n",
+		},
+		{
+			name:  "list bullets and task boxes",
+			input: "- one ,two\n* [x] a apple\n10. last .\n",
+			want:  "- one, two\n* [x] an apple\n10. last.\n",
+		},
+		{
+			name:  "block quote",
+			input: "> a quote ,here\n",
+			want:  "> a quote, here\n",
+		},
+		{
+			name:  "fenced code untouched",
+			input: "```go\nfmt.Println(\"a , b\") (up)\n```\n~~~\nx ,y\n~~~\nafter ,code\n",
+			want:  "```go\nfmt.Println(\"a , b\") (up)\n```\n~~~\nx ,y\n~~~\nafter, code\n",
+		},
+		{
+			name:  "indented code untouched",
+			input: "text ,here\n\n    raw , code\n",
+			want:  "text, here\n\n    raw , code\n",
+		},
+		{
+			name:  "front matter untouched",
+			input: "---\ntitle: a , b\n---\nbody ,text\n",
+			want:  "---\ntitle: a , b\n---\nbody, text\n",
+		},
+		{
+			name:  "links and images keep their destinations",
+			input: "see [the docs ,here](http://x.io/a_b,c (up)) and ![a image](a,b.png) .\n",
+			want:  "see [the docs, here](http://x.io/a_b,c (up)) and ![an image](a,b.png).\n",
+		},
+		{
+			name:  "reference links and definitions",
+			input: "see [docs][a , b] now .\n[a , b]: http://x.io \"t , x\"\n",
+			want:  "see [docs][a , b] now.\n[a , b]: http://x.io \"t , x\"\n",
+		},
+		{
+			name:  "code spans and inline html",
+			input: "use `a , b` and <kbd>Ctrl ,C</kbd> .\n",
+			want:  "use `a , b` and <kbd>Ctrl, C</kbd>.\n",
+		},
+		{
+			name:  "comment block ends at its closing delimiter",
+			input: "<!-- note -->\nbad , spacing and a apple\n\n<!--\na , b\n-->\nc ,d\n",
+			want:  "<!-- note -->\nbad, spacing and an apple\n\n<!--\na , b\n-->\nc, d\n",
+		},
+		{
+			name:  "pre block ends at its end tag",
+			input: "<pre>\na , b\n\nc , d\n</pre>\ne ,f\n",
+			want:  "<pre>\na , b\n\nc , d\n</pre>\ne, f\n",
+		},
+		{
+			name:  "articles and markers reach across inline markup",
+			input: "it was a <em>apple</em> ,<b>really</b> (up) here\n",
+			want:  "it was an <em>apple</em>, <b>REALLY</b> here\n",
+		},
+		{
+			name:  "html block untouched",
+			input: "<div class=\"x\">\na , b\n</div>\n\nafter ,it\n",
+			want:  "<div class=\"x\">\na , b\n</div>\n\nafter, it\n",
+		},
+		{
+			name:  "tables format cells only",
+			input: "| a apple ,x | `y , z` |\n|:--|--:|\n| b | c ,d |\n",
+			want:  "| an apple, x | `y , z` |\n|:--|--:|\n| b | c, d |\n",
+		},
+		{
+			name:  "hard breaks and thematic breaks",
+			input: "line one  \n* * *\nline ,two\r\n",
+			want:  "line one  \n* * *\nline, two\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Markdown(tt.input, format)
+			if err != nil {
+				t.Fatalf("Markdown() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Markdown():\n got  %q\n want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdown_WrappedParagraph(t *testing.T) {
+	pl, err := pipeline.NewWithOptions(pipeline.Options{SentenceCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := func(text string) string {
+		out, err := pl.Format(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", text, err)
+		}
+		return out
+	}
+
+	// A paragraph is one text whatever its wrapping: sentences, markers
+	// and articles reach across the line breaks, which stay where they are
+	input := "a paragraph that wraps\nonto the next line. it was a\napple ,really\n(up) and\n\n> a quote\n> that wraps (cap)\n- an item ,which\n  wraps\n- b\n"
+	want := "A paragraph that wraps\nonto the next line. It was an\napple, REALLY\nand\n\n> A quote\n> that Wraps\n- An item, which\n  wraps\n- B\n"
+	got, err := Markdown(input, format)
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("Markdown():\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestMarkdown_InlineMarkup(t *testing.T) {
+	pl, err := pipeline.NewWithOptions(pipeline.Options{SentenceCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := func(text string) string {
+		out, err := pl.Format(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", text, err)
+		}
+		return out
+	}
+
+	// Links and inline tags sit inside a sentence rather than end it
+	input := "see [the docs](http://x.y/a) and a <em>apple</em> here. then [more](http://x.y/b)\n"
+	want := "See [the docs](http://x.y/a) and an <em>apple</em> here. Then [more](http://x.y/b)\n"
+	got, err := Markdown(input, format)
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("Markdown():\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestMarkdown_Unchanged(t *testing.T) {
+	// A document without prose to fix must come back byte for byte
+	input := "# Title\n\n- item\n\n```sh\nls -la  \n```\n\n[link](<a b>)\n"
+	got, err := Markdown(input, defaultFormat(t))
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+	if got != input {
+		t.Errorf("Markdown():\n got  %q\n want %q", got, input)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"text", "Markdown"} {
+		if _, ok := Lookup(name, Options{}); !ok {
+			t.Errorf("Lookup(%q) not found", name)
+		}
+	}
+	if _, ok := Lookup("docx", Options{}); ok {
+		t.Error("Lookup(\"docx\") should fail")
+	}
+}
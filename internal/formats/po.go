@@ -0,0 +1,122 @@
+package formats
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// poKeyword matches the start of a PO entry field and captures its keyword,
+// e.g. msgid, msgid_plural, msgctxt, msgstr or msgstr[1].
+var poKeyword = regexp.MustCompile(`^\s*(msgctxt|msgid_plural|msgid|msgstr(?:\[\d+\])?)\s+"`)
+
+// PO formats the translations of a gettext catalog. Only msgstr values are
+// formatted, plural forms included; comments, contexts, source strings and
+// the header entry are copied through unchanged.
+func PO(src string, format TextFunc) (string, error) {
+	lines := strings.SplitAfter(src, "\n")
+	var out strings.Builder
+	out.Grow(len(src))
+
+	header := false // the current entry has an empty msgid
+	for i := 0; i < len(lines); {
+		m := poKeyword.FindStringSubmatch(lines[i])
+		if m == nil {
+			out.WriteString(lines[i])
+			i++
+			continue
+		}
+
+		// A field continues over the following lines that hold only a string
+		end := i + 1
+		for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), `"`) {
+			end++
+		}
+		field := lines[i:end]
+		keyword := m[1]
+
+		value, err := poValue(field, len(m[0])-1)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch {
+		case keyword == "msgctxt":
+			header = false
+		case keyword == "msgid":
+			header = value == ""
+		case strings.HasPrefix(keyword, "msgstr") && !header:
+			if formatted := formatLines(value, format); formatted != value {
+				rewritten := poField(lines[i][:len(m[0])-1], formatted, len(field) > 1, lineEnding(lines[i]))
+				if lineEnding(lines[end-1]) == "" {
+					// The file ended without a final newline
+					rewritten = strings.TrimRight(rewritten, "\r\n")
+				}
+				out.WriteString(rewritten)
+				i = end
+				continue
+			}
+		}
+
+		for _, l := range field {
+			out.WriteString(l)
+		}
+		i = end
+	}
+
+	return out.String(), nil
+}
+
+// poValue joins the quoted strings of a field. The first line's string
+// starts at offset start.
+func poValue(field []string, start int) (string, error) {
+	var value strings.Builder
+	for n, line := range field {
+		s := strings.TrimSpace(line)
+		if n == 0 {
+			s = strings.TrimSpace(line[start:])
+		}
+		part, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		value.WriteString(part)
+	}
+	return value.String(), nil
+}
+
+// poField writes a field back. A field that spanned several lines starts
+// with an empty string and continues with one line per line of the value.
+func poField(prefix, value string, multiline bool, eol string) string {
+	if !multiline {
+		return prefix + poQuote(value) + eol
+	}
+	var out strings.Builder
+	out.WriteString(prefix + `""` + eol)
+	for _, part := range strings.SplitAfter(value, "\n") {
+		if part != "" {
+			out.WriteString(poQuote(part) + eol)
+		}
+	}
+	return out.String()
+}
+
+var poEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\t", `\t`,
+	"\r", `\r`,
+)
+
+// poQuote quotes s with the C escapes gettext understands, leaving other
+// characters as UTF-8.
+func poQuote(s string) string {
+	return `"` + poEscaper.Replace(s) + `"`
+}
+
+// lineEnding returns the line terminator of line, or "" for the last line.
+func lineEnding(line string) string {
+	return line[len(strings.TrimRight(line, "\r\n")):]
+}
@@ -0,0 +1,91 @@
+package formats
+
+import "testing"
+
+func TestPO(t *testing.T) {
+	format := defaultFormat(t)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "header untouched",
+			input: "msgid \"\"\nmsgstr \"\"\n\"Project-Id-Version: a , b\\n\"\n",
+			want:  "msgid \"\"\nmsgstr \"\"\n\"Project-Id-Version: a , b\\n\"\n",
+		},
+		{
+			name:  "single line with placeholders",
+			input: "# a , comment\n#: main.go:12\nmsgid \"%s files ,done\"\nmsgstr \"%s fichiers ,faits  {name} !\"\n",
+			want:  "# a , comment\n#: main.go:12\nmsgid \"%s files ,done\"\nmsgstr \"%s fichiers, faits {name}!\"\n",
+		},
+		{
+			name:  "multi-line string",
+			input: "msgid \"x\"\nmsgstr \"\"\n\"it was a apple ,\"\n\"really .\\n\"\n\"next line\"\n",
+			want:  "msgid \"x\"\nmsgstr \"\"\n\"it was an apple, really.\\n\"\n\"next line\"\n",
+		},
+		{
+			name:  "plural forms and escapes",
+			input: "msgctxt \"menu\"\nmsgid \"one\"\nmsgid_plural \"many\"\nmsgstr[0] \"one \\\"item\\\" ,ok\"\nmsgstr[1] \"{{count}} items ,ok\"\n",
+			want:  "msgctxt \"menu\"\nmsgid \"one\"\nmsgid_plural \"many\"\nmsgstr[0] \"one \\\"item\\\", ok\"\nmsgstr[1] \"{{count}} items, ok\"\n",
+		},
+		{
+			name:  "obsolete entries untouched",
+			input: "#~ msgid \"a\"\n#~ msgstr \"b ,c\"\n",
+			want:  "#~ msgid \"a\"\n#~ msgstr \"b ,c\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PO(tt.input, format)
+			if err != nil {
+				t.Fatalf("PO() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PO():\n got  %q\n want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := PO("msgid \"a\"\nmsgstr \"unterminated\n", format); err == nil {
+		t.Error("expected error for an unterminated string")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	format := defaultFormat(t)
+
+	input := `{
+  "greeting ,key": "hello ,{name} !",
+  "count": 3,
+  "nested": {
+    "items": ["a apple ,x", "<b>ok</b> ,y", true],
+    "empty": ""
+  },
+  "last": "fine"
+}
+`
+	want := `{
+  "greeting ,key": "hello, {name}!",
+  "count": 3,
+  "nested": {
+    "items": ["an apple, x", "<b>ok</b>, y", true],
+    "empty": ""
+  },
+  "last": "fine"
+}
+`
+	got, err := JSON(input, format)
+	if err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("JSON():\n got  %s\n want %s", got, want)
+	}
+
+	if _, err := JSON(`{"a": `, format); err == nil {
+		t.Error("expected error for truncated JSON")
+	}
+}
@@ -0,0 +1,146 @@
+package formats
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// Run is a run of prose a handler passed to its TextFunc, located in the
+// document it came from.
+type Run struct {
+	Text string
+	// start is where Text begins in the document when it appears there
+	// as it is; otherwise offsets holds the offset of each byte of Text,
+	// and one past its end
+	start   int
+	offsets []int
+}
+
+// Offset returns the byte offset in the document of byte i of r.Text.
+// i may be len(r.Text), for the end of the run.
+func (r Run) Offset(i int) int {
+	if r.offsets == nil {
+		return r.start + i
+	}
+	return r.offsets[i]
+}
+
+// alignWindow bounds how far Locate looks ahead in the document for the
+// next character of a run that the handler decoded, as from "&hellip;"
+// or "é", or joined, as across a Markdown line prefix.
+const alignWindow = 16
+
+// Tracker locates in a document the runs of prose a handler passes to its
+// TextFunc. Handlers pass them in document order.
+type Tracker struct {
+	src  string
+	next int // where the search for the next run starts
+}
+
+// NewTracker returns a Tracker for the runs of src.
+func NewTracker(src string) *Tracker {
+	return &Tracker{src: src}
+}
+
+// Locate returns text, the next run of prose, located in the document. A
+// run that does not appear in the document as it is, because the handler
+// decoded or joined it, is matched up character by character; characters
+// the document spells another way are placed where their spelling starts,
+// and a Markup placeholder where the markup it stands for starts.
+func (t *Tracker) Locate(text string) Run {
+	rest := t.src[t.next:]
+	if i := strings.Index(rest, text); i >= 0 {
+		run := Run{Text: text, start: t.next + i}
+		t.next += i + len(text)
+		return run
+	}
+
+	pos := t.next + anchor(rest, text)
+	offsets := make([]int, len(text)+1)
+	for i, r := range text {
+		size := utf8.RuneLen(r)
+		want := string(r)
+		if r == tokenizer.SoftBreak {
+			want = "\n"
+		}
+		if r == tokenizer.Markup {
+			// The markup the placeholder stands for may run any length;
+			// the prose after it picks up where it is found again
+			for b := range size {
+				offsets[i+b] = pos
+			}
+			pos += resume(t.src[pos:], text[i+size:])
+			continue
+		}
+		ahead := t.src[pos:min(pos+alignWindow, len(t.src))]
+		if k := strings.Index(ahead, want); k >= 0 {
+			pos += k
+			for b := range size {
+				offsets[i+b] = pos + min(b, len(want)-1)
+			}
+			pos += len(want)
+		} else {
+			for b := range size {
+				offsets[i+b] = pos
+			}
+		}
+	}
+	offsets[len(text)] = pos
+	t.next = pos
+	return Run{Text: text, offsets: offsets}
+}
+
+// anchor guesses where text starts in rest from the first word of text,
+// which a handler does not decode.
+func anchor(rest, text string) int {
+	start := strings.IndexFunc(text, isWordRune)
+	if start < 0 {
+		return 0
+	}
+	end := strings.IndexFunc(text[start:], func(r rune) bool { return !isWordRune(r) })
+	if end < 0 {
+		end = len(text) - start
+	}
+	k := strings.Index(rest, text[start:start+end])
+	if k < 0 {
+		return 0
+	}
+	// Whatever comes before the word is spelled at least as long
+	return max(k-start, 0)
+}
+
+// resume returns where in rest the prose of text, which follows a Markup
+// placeholder, starts again.
+func resume(rest, text string) int {
+	if end := strings.IndexRune(text, tokenizer.Markup); end >= 0 {
+		text = text[:end]
+	}
+	if text == "" {
+		return 0
+	}
+	if k := strings.Index(rest, text); k >= 0 {
+		return k
+	}
+	return anchor(rest, text)
+}
+
+func isWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Runs returns the runs of prose h finds in src, in document order.
+func Runs(h Handler, src string) ([]Run, error) {
+	t := NewTracker(src)
+	var runs []Run
+	_, err := h(src, func(text string) string {
+		runs = append(runs, t.Locate(text))
+		return text
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,69 @@
+package formats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuns(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+		want   []string // each run as the document spells it
+	}{
+		{
+			name:   "text",
+			format: "text",
+			input:  "a apple ,ok",
+			want:   []string{"a apple ,ok"},
+		},
+		{
+			name:   "html skips markup",
+			format: "html",
+			input:  "<p title=\"ok\">ok ,then</p><pre>x</pre><p>more</p>",
+			want:   []string{"ok ,then", "more"},
+		},
+		{
+			name:   "html entities",
+			format: "html",
+			input:  "<p>caf&eacute; &amp; tea</p>",
+			want:   []string{"caf&eacute; &amp; tea"},
+		},
+		{
+			name:   "html inline tags",
+			format: "html",
+			input:  "<p>a <a href=\"http://example.com/a/long/path\">apple</a> ,ok</p>",
+			want:   []string{"a <a href=\"http://example.com/a/long/path\">apple</a> ,ok"},
+		},
+		{
+			name:   "markdown link",
+			format: "markdown",
+			input:  "see [the docs](http://example.com/a/long/path) ,now\n",
+			want:   []string{"see [the docs](http://example.com/a/long/path) ,now"},
+		},
+		{
+			name:   "markdown paragraph across quoted lines",
+			format: "markdown",
+			input:  "# Title\n\n> one\n> two\n",
+			want:   []string{"Title", "one\n> two"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := Lookup(tt.format, Options{})
+			runs, err := Runs(h, tt.input)
+			if err != nil {
+				t.Fatalf("Runs() error = %v", err)
+			}
+			var got []string
+			for _, run := range runs {
+				got = append(got, tt.input[run.Offset(0):run.Offset(len(run.Text))])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Runs(%q):\n got  %q\n want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package formats
+
+import (
+	"strings"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// SRT formats the cue text of a SubRip subtitle file. Cue numbers and
+// timings are copied through unchanged.
+func SRT(src string, format TextFunc) (string, error) {
+	return subtitles(src, format, false), nil
+}
+
+// VTT formats the cue text of a WebVTT file. The header, cue identifiers,
+// timings with their settings, and NOTE, STYLE and REGION blocks are copied
+// through unchanged.
+func VTT(src string, format TextFunc) (string, error) {
+	return subtitles(src, format, true), nil
+}
+
+// subtitles walks the blank-line separated blocks of a subtitle file and
+// formats the text lines after a block's timing line as one run, so
+// markers reach across the lines of a cue but never into another cue.
+func subtitles(src string, format TextFunc, vtt bool) string {
+	var out strings.Builder
+	out.Grow(len(src))
+
+	if strings.HasPrefix(src, "\uFEFF") {
+		out.WriteString("\uFEFF")
+		src = src[len("\uFEFF"):]
+	}
+
+	first := true // at the first line of a block
+	inText := false
+	verbatim := false
+
+	var cue, eols []string // the text lines of the cue being read
+	flush := func() {
+		for i, line := range formatCue(cue, format) {
+			if line != "" {
+				out.WriteString(line)
+				out.WriteString(eols[i])
+			}
+		}
+		cue, eols = cue[:0], eols[:0]
+	}
+
+	for _, line := range strings.SplitAfter(src, "\n") {
+		body := strings.TrimRight(line, "\r\n")
+		eol := line[len(body):]
+
+		if strings.TrimSpace(body) == "" {
+			flush()
+			out.WriteString(line)
+			first, inText, verbatim = true, false, false
+			continue
+		}
+		if first && vtt && isVTTMetadata(body) {
+			verbatim = true
+		}
+		first = false
+
+		switch {
+		case verbatim:
+			out.WriteString(line)
+		case inText:
+			cue = append(cue, body)
+			eols = append(eols, eol)
+		default:
+			// Cue numbers, identifiers and the timing line
+			out.WriteString(line)
+			inText = strings.Contains(body, "-->")
+		}
+	}
+	flush()
+
+	return out.String()
+}
+
+// isVTTMetadata reports whether a block starting with line is the WebVTT
+// header or a comment, style or region block rather than a cue.
+func isVTTMetadata(line string) bool {
+	for _, kw := range []string{"WEBVTT", "NOTE", "STYLE", "REGION"} {
+		if line == kw || strings.HasPrefix(line, kw+" ") || strings.HasPrefix(line, kw+"\t") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCue formats the text lines of one cue as one run, joined with
+// tokenizer.SoftBreak. Markup such as <i> or <v Speaker> is kept as is. A
+// line that comes out blank, as one holding only a marker does, is
+// returned as "" to be dropped, since a blank line would end the cue;
+// if every line would, the cue is left alone.
+func formatCue(lines []string, format TextFunc) []string {
+	texts := make([]string, len(lines))
+	tags := make([][]string, len(lines))
+	for i, line := range lines {
+		texts[i], tags[i] = cueMarkup(line)
+	}
+	each := func(s string) string { return formatText(s, format) }
+	formatted := formatPieces(texts, tokenizer.SoftBreak, each, each)
+
+	out := make([]string, len(lines))
+	blank := true
+	for i, line := range lines {
+		pieces := strings.Split(formatted[i], string(tokenizer.Markup))
+		if len(pieces) != len(tags[i])+1 {
+			out[i] = line
+			blank = false
+			continue
+		}
+		var b strings.Builder
+		for k, piece := range pieces {
+			b.WriteString(piece)
+			if k < len(tags[i]) {
+				b.WriteString(tags[i][k])
+			}
+		}
+		if result := strings.TrimRight(b.String(), " \t"); result != "" {
+			// Keep the line's own trailing whitespace, not what a dropped
+			// marker left behind
+			out[i] = result + line[len(strings.TrimRight(line, " \t")):]
+			blank = false
+		}
+	}
+	if blank {
+		return lines
+	}
+	return out
+}
+
+// cueMarkup returns line with tokenizer.Markup in place of each tag, and
+// the tags it replaced.
+func cueMarkup(line string) (string, []string) {
+	var out strings.Builder
+	var tags []string
+	textStart := 0
+	for i := 0; i < len(line); i++ {
+		if line[i] != '<' {
+			continue
+		}
+		end := strings.IndexByte(line[i:], '>')
+		if end < 0 {
+			break
+		}
+		out.WriteString(line[textStart:i])
+		out.WriteRune(tokenizer.Markup)
+		tags = append(tags, line[i:i+end+1])
+		i += end
+		textStart = i + 1
+	}
+	out.WriteString(line[textStart:])
+	return out.String(), tags
+}
@@ -0,0 +1,85 @@
+package formats
+
+import (
+	"context"
+	"testing"
+
+	"go-reloaded/internal/pipeline"
+)
+
+func TestSRT(t *testing.T) {
+	input := "1\r\n00:00:01,000 --> 00:00:02,500\r\nit was a apple ,really .\r\n<i>so</i> , good (up)\r\n\r\n" +
+		"2\r\n00:00:03,000 --> 00:00:04,000\r\nthis one (up, 3)\r\n"
+	want := "1\r\n00:00:01,000 --> 00:00:02,500\r\nit was an apple, really.\r\n<i>so</i>, GOOD\r\n\r\n" +
+		"2\r\n00:00:03,000 --> 00:00:04,000\r\nTHIS ONE\r\n"
+
+	got, err := SRT(input, defaultFormat(t))
+	if err != nil {
+		t.Fatalf("SRT() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("SRT():\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestVTT(t *testing.T) {
+	input := "\uFEFFWEBVTT - a , b\n\nNOTE a , b\nstill a note ,here\n\nSTYLE\n::cue { color: red , blue }\n\n" +
+		"intro\n00:01.000 --> 00:02.000 align:start position:10%\n<v Roger>hello ,there</v>\n(up)\n"
+	want := "\uFEFFWEBVTT - a , b\n\nNOTE a , b\nstill a note ,here\n\nSTYLE\n::cue { color: red , blue }\n\n" +
+		"intro\n00:01.000 --> 00:02.000 align:start position:10%\n<v Roger>hello, THERE</v>\n"
+
+	got, err := VTT(input, defaultFormat(t))
+	if err != nil {
+		t.Fatalf("VTT() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("VTT():\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestSRT_Cues(t *testing.T) {
+	pl, err := pipeline.NewWithOptions(pipeline.Options{SentenceCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := func(text string) string {
+		out, err := pl.Format(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", text, err)
+		}
+		return out
+	}
+
+	// The lines of a cue are one text; a cue is never reached from another
+	input := "1\n00:00:01,000 --> 00:00:02,000\ni was going\nto the store\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\nit was a big\n<i>red</i> (up, 2) apple\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\n(up)\n"
+	want := "1\n00:00:01,000 --> 00:00:02,000\nI was going\nto the store\n\n" +
+		"2\n00:00:03,000 --> 00:00:04,000\nIt was a BIG\n<i>RED</i> apple\n\n" +
+		"3\n00:00:05,000 --> 00:00:06,000\n(up)\n"
+	got, err := SRT(input, format)
+	if err != nil {
+		t.Fatalf("SRT() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("SRT():\n got  %q\n want %q", got, want)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		"notes.txt":       "text",
+		"README.md":       "markdown",
+		"page.HTML":       "html",
+		"movie.en.srt":    "srt",
+		"captions.vtt":    "vtt",
+		"no-extension":    "text",
+		"dir.md/file.txt": "text",
+		"main.go":         "go",
+	}
+	for path, want := range tests {
+		if got := Detect(path); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
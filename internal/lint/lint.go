@@ -0,0 +1,178 @@
+// Package lint reports what formatting would change in a file without
+// changing it.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go-reloaded/internal/formats"
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/internal/report"
+	"go-reloaded/pkg/tokenizer"
+)
+
+// Options selects the rules a Linter checks. Every rule is on unless
+// disabled; Enable turns rules back on after Disable. A name selects the
+// rule with that ID, or every rule whose ID starts with the name and a dash,
+// so "punctuation" selects "punctuation-spacing".
+type Options struct {
+	Disable []string
+	Enable  []string
+}
+
+// Linter checks files against a set of rules.
+type Linter struct {
+	pl      *pipeline.Pipeline
+	enabled map[string]bool
+}
+
+// New returns a Linter that runs the stages of pl to find fixable problems.
+// Each of opts is applied in turn, so later ones win, e.g. flags over a
+// configuration file.
+func New(pl *pipeline.Pipeline, opts ...Options) (*Linter, error) {
+	l := &Linter{pl: pl, enabled: make(map[string]bool, len(report.Rules))}
+	for _, rule := range report.Rules {
+		l.enabled[rule.ID] = true
+	}
+	for _, o := range opts {
+		if err := l.set(o.Disable, false); err != nil {
+			return nil, err
+		}
+		if err := l.set(o.Enable, true); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *Linter) set(names []string, on bool) error {
+	for _, name := range names {
+		ids := ruleIDs(name)
+		if len(ids) == 0 {
+			return fmt.Errorf("unknown rule %q", name)
+		}
+		for _, id := range ids {
+			l.enabled[id] = on
+		}
+	}
+	return nil
+}
+
+// ruleIDs returns the IDs of the rules name selects.
+func ruleIDs(name string) []string {
+	var ids []string
+	for _, rule := range report.Rules {
+		if selects(name, rule.ID) {
+			ids = append(ids, rule.ID)
+		}
+	}
+	return ids
+}
+
+// selects reports whether a rule name given by the user selects rule id.
+func selects(name, id string) bool {
+	return name == id || strings.HasPrefix(id, name+"-")
+}
+
+// Lint returns the problems in src, in order of position, leaving out
+// disabled rules and suppressed regions. Only the runs of prose h passes
+// on for formatting are checked, so markup and code are never reported.
+func (l *Linter) Lint(src string, h formats.Handler) ([]report.Diagnostic, error) {
+	runs, err := formats.Runs(h, src)
+	if err != nil {
+		return nil, err
+	}
+	loc := report.NewLocator(src)
+	var diags []report.Diagnostic
+	for _, run := range runs {
+		found := report.Paragraphs(l.pl, run.Text, func(tokens []tokenizer.Token) []report.Diagnostic {
+			var found []report.Diagnostic
+			found = append(found, report.Diagnose(tokens, l.pl.Macros()...)...)
+			found = append(found, report.Check(l.pl.Stages(), tokens)...)
+			return append(found, report.Leftovers(tokens, l.pl.Macros()...)...)
+		})
+		diags = append(diags, loc.Diagnostics(run, found)...)
+	}
+
+	suppressed := suppressions(src)
+	kept := diags[:0]
+	for _, d := range diags {
+		if l.enabled[d.Rule] && !isSuppressed(suppressed, d) {
+			kept = append(kept, d)
+		}
+	}
+	// NewFile sorts the diagnostics it is given
+	return report.NewFile("", "", nil, kept).Diagnostics, nil
+}
+
+// A suppression turns off rules, or all rules if none are listed, for
+// diagnostics that start in [start, end).
+type suppression struct {
+	rules      []string
+	start, end position
+}
+
+type position struct {
+	line, col int
+}
+
+func (p position) before(q position) bool {
+	return p.line < q.line || p.line == q.line && p.col < q.col
+}
+
+var (
+	// ignoreComment suppresses the rules it lists on its own line and the
+	// next one
+	ignoreComment = regexp.MustCompile(`<!--\s*textfmt:ignore\b([^>]*?)\s*-->`)
+	// noformatRegion suppresses everything up to (/noformat), or to the end
+	// of the file if it is never closed
+	noformatRegion = regexp.MustCompile(`(?is)\(noformat\).*?(?:\(/noformat\)|\z)`)
+)
+
+// suppressions finds the ignore comments and (noformat) regions in src.
+func suppressions(src string) []suppression {
+	var s []suppression
+	for _, m := range ignoreComment.FindAllStringSubmatchIndex(src, -1) {
+		start, end := positionAt(src, m[0]), positionAt(src, m[1])
+		// The comment itself is never worth reporting
+		s = append(s, suppression{start: start, end: end})
+		s = append(s, suppression{
+			rules: strings.Fields(src[m[2]:m[3]]),
+			start: position{start.line, 1},
+			end:   position{end.line + 2, 1},
+		})
+	}
+	for _, m := range noformatRegion.FindAllStringIndex(src, -1) {
+		s = append(s, suppression{start: positionAt(src, m[0]), end: positionAt(src, m[1])})
+	}
+	return s
+}
+
+func isSuppressed(suppressed []suppression, d report.Diagnostic) bool {
+	at := position{d.Position.Line, d.Position.Column}
+	for _, s := range suppressed {
+		if at.before(s.start) || !at.before(s.end) {
+			continue
+		}
+		if len(s.rules) == 0 {
+			return true
+		}
+		for _, name := range s.rules {
+			if selects(name, d.Rule) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// positionAt returns the line and column, from 1, of byte offset off in
+// src. Columns count runes, as in report.Region.
+func positionAt(src string, off int) position {
+	before := src[:off]
+	line := strings.Count(before, "\n") + 1
+	return position{line, utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1}
+}
@@ -0,0 +1,136 @@
+package lint
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go-reloaded/internal/formats"
+	"go-reloaded/internal/pipeline"
+)
+
+func TestLinter_Lint(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		format string // empty means text
+		input  string
+		want   []string // "line:col rule"
+	}{
+		{
+			name:  "clean",
+			input: "An apple, a pear and two figs.",
+			want:  nil,
+		},
+		{
+			name:  "all rules",
+			input: "a apple ,ok (up) ' hi ' (upx)",
+			want: []string{
+				"1:1 article-mismatch",
+				"1:8 punctuation-spacing",
+				"1:10 punctuation-spacing",
+				"1:13 leftover-marker",
+				"1:17 punctuation-spacing",
+				"1:19 quote-spacing",
+				"1:22 quote-spacing",
+				"1:25 malformed-marker",
+			},
+		},
+		{
+			name:  "disabled by prefix",
+			opts:  Options{Disable: []string{"punctuation", "leftover-marker"}},
+			input: "a apple ,ok (up)",
+			want:  []string{"1:1 article-mismatch"},
+		},
+		{
+			name:  "enable after disable",
+			opts:  Options{Disable: []string{"article", "punctuation"}, Enable: []string{"article-mismatch"}},
+			input: "a apple ,ok",
+			want:  []string{"1:1 article-mismatch"},
+		},
+		{
+			name:  "ignore comment covers its line and the next",
+			input: "<!-- textfmt:ignore punctuation -->\nwait ,what a apple\nwait ,what",
+			want: []string{
+				"2:12 article-mismatch",
+				"3:5 punctuation-spacing",
+				"3:7 punctuation-spacing",
+			},
+		},
+		{
+			name:  "ignore comment without rules",
+			input: "wait ,what a apple <!-- textfmt:ignore -->",
+			want:  nil,
+		},
+		{
+			name:  "noformat region",
+			input: "(noformat) a apple ,x (/noformat) a apple\n(noformat)\nwait ,what",
+			want:  []string{"1:35 article-mismatch"},
+		},
+		{
+			name:   "go code is not prose",
+			format: "go",
+			input:  "package main\n\n// wait ,what\nfunc main() {\n\tx := a(1 , 2)\n\tfmt.Println(x)\n}\n",
+			want:   []string{"3:8 punctuation-spacing", "3:10 punctuation-spacing"},
+		},
+		{
+			name:   "html attributes and pre",
+			format: "html",
+			input:  "<p title=\"wait ,what\">a apple</p>\n<pre>x ,y</pre>\n<p>caf&eacute; &amp; wait ,what</p>",
+			want: []string{
+				"1:23 article-mismatch",
+				"3:26 punctuation-spacing",
+				"3:28 punctuation-spacing",
+			},
+		},
+		{
+			name:   "markdown paragraph",
+			format: "markdown",
+			input:  "# A title\n\n> first line\n> wait ,what\n",
+			want:   []string{"4:7 punctuation-spacing", "4:9 punctuation-spacing"},
+		},
+		{
+			name:   "markdown ignore comment",
+			format: "markdown",
+			input:  "<!-- textfmt:ignore punctuation -->\nbad , spacing and a apple\nwait ,what\n",
+			want: []string{
+				"2:19 article-mismatch",
+				"3:5 punctuation-spacing",
+				"3:7 punctuation-spacing",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := New(pipeline.New(), tt.opts)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			h := formats.Handler(formats.Text)
+			if tt.format != "" {
+				h, _ = formats.Lookup(tt.format, formats.Options{})
+			}
+			diags, err := l.Lint(tt.input, h)
+			if err != nil {
+				t.Fatalf("Lint() error = %v", err)
+			}
+			var got []string
+			for _, d := range diags {
+				got = append(got, fmt.Sprintf("%d:%d %s", d.Position.Line, d.Position.Column, d.Rule))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint(%q):\n got  %v\n want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_UnknownRule(t *testing.T) {
+	if _, err := New(pipeline.New(), Options{Disable: []string{"spelling"}}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+	if _, err := New(pipeline.New(), Options{Enable: []string{"article-"}}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
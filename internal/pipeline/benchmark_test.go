@@ -1,6 +1,9 @@
 package pipeline
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 
 	"go-reloaded/pkg/tokenizer"
@@ -14,7 +17,9 @@ func BenchmarkPipeline(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = pl.Process(tokens)
+		if _, err := pl.Process(context.Background(), tokens); err != nil {
+			b.Fatal(err)
+		}
 	}
 }
 
@@ -26,7 +31,10 @@ func BenchmarkTokenizeAndProcess(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		tokens := tokenizer.Tokenize(input)
-		processed := pl.Process(tokens)
+		processed, err := pl.Process(context.Background(), tokens)
+		if err != nil {
+			b.Fatalf("Process() error = %v", err)
+		}
 
 		// Rebuild string to simulate full pipeline
 		var result string
@@ -35,3 +43,23 @@ func BenchmarkTokenizeAndProcess(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkFormatJobs(b *testing.B) {
+	paragraph := "there (cap) once was a hero named link (cap, 3), he carried 1e (hex) rupees and 10 (bin) arrows. he said: ' this is a honor ' before entering a old temple (up, 2) !"
+	input := strings.Repeat(paragraph+"\n\n", 2000)
+
+	for _, jobs := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			pl, err := NewWithOptions(Options{Jobs: jobs})
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				if _, err := pl.Format(context.Background(), input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
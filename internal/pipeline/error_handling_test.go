@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"context"
 	"testing"
 
 	"go-reloaded/pkg/tokenizer"
@@ -45,7 +46,10 @@ func TestPipelineErrorHandling(t *testing.T) {
 			pl := New()
 
 			// Should not panic
-			processed := pl.Process(tokens)
+			processed, err := pl.Process(context.Background(), tokens)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
 
 			// Rebuild string
 			var result string
@@ -82,7 +86,10 @@ func TestPipelineStability(t *testing.T) {
 
 			tokens := tokenizer.Tokenize(input)
 			pl := New()
-			processed := pl.Process(tokens)
+			processed, err := pl.Process(context.Background(), tokens)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
 
 			// Should produce some output (even if empty)
 			var result string
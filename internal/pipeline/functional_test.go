@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"context"
 	"testing"
 
 	"go-reloaded/pkg/tokenizer"
@@ -15,7 +16,10 @@ func TestPipelineEndToEnd(t *testing.T) {
 
 	// Process through pipeline
 	pl := New()
-	processed := pl.Process(tokens)
+	processed, err := pl.Process(context.Background(), tokens)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
 
 	// Rebuild string
 	var result string
@@ -44,7 +48,10 @@ func TestPipelineProcessorOrder(t *testing.T) {
 	tokens := tokenizer.Tokenize(input)
 
 	pl := New()
-	result := pl.Process(tokens)
+	result, err := pl.Process(context.Background(), tokens)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
 
 	// Rebuild string
 	var output string
@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"context"
 	"testing"
 
 	"go-reloaded/pkg/tokenizer"
@@ -51,7 +52,10 @@ func TestIntegrationPipeline(t *testing.T) {
 
 			// Process through pipeline
 			pl := New()
-			processed := pl.Process(tokens)
+			processed, err := pl.Process(context.Background(), tokens)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
 
 			// Rebuild string
 			var result string
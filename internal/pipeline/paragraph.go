@@ -0,0 +1,177 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// paragraphBreak matches the line breaks between two paragraphs: at least
+// two, with nothing but spaces and tabs on the lines between.
+var paragraphBreak = regexp.MustCompile(`\r?\n(?:[ \t]*\r?\n)+`)
+
+// Paragraph is a run of text between paragraph breaks. Format formats
+// each paragraph on its own, so nothing carries over a blank line.
+type Paragraph struct {
+	Text string
+	Line int // line of the text it starts on, from 1
+}
+
+// SplitParagraphs splits text at paragraph breaks and returns the
+// paragraphs and the breaks between them, so that joining them in turn
+// gives text back. Every paragraph after the first starts a line.
+func SplitParagraphs(text string) ([]Paragraph, []string) {
+	locs := paragraphBreak.FindAllStringIndex(text, -1)
+	paras := make([]Paragraph, 0, len(locs)+1)
+	breaks := make([]string, 0, len(locs))
+	start, line := 0, 1
+	for _, loc := range locs {
+		paras = append(paras, Paragraph{text[start:loc[0]], line})
+		breaks = append(breaks, text[loc[0]:loc[1]])
+		line += strings.Count(text[start:loc[1]], "\n")
+		start = loc[1]
+	}
+	return append(paras, Paragraph{text[start:], line}), breaks
+}
+
+// formatText formats the paragraphs of text one after the other, stage by
+// stage, so that a trace sees every stage over the whole text.
+func (p *Pipeline) formatText(ctx context.Context, text string) (string, error) {
+	paras, breaks := SplitParagraphs(text)
+	tokens := make([][]tokenizer.Token, len(paras))
+	for i, para := range paras {
+		tokens[i] = p.Tokenize(para.Text)
+	}
+	tokens, failed, err := p.process(ctx, tokens, breaks)
+	if err != nil {
+		if failed >= 0 && len(paras) > 1 {
+			return "", fmt.Errorf("paragraph at line %d: %w", paras[failed].Line, err)
+		}
+		return "", err
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	for i, para := range tokens {
+		for _, tok := range para {
+			b.WriteString(tok.Value)
+		}
+		if i < len(breaks) {
+			b.WriteString(breaks[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// process runs the tokens of each paragraph through every stage in turn.
+// A trace gets the paragraphs joined by the breaks between them, as Space
+// tokens. It stops at the first stage that fails, or once ctx is done,
+// and returns the error prefixed with the name of the stage, along with
+// the index of the paragraph that failed, or -1 if none did.
+func (p *Pipeline) process(ctx context.Context, paras [][]tokenizer.Token, breaks []string) ([][]tokenizer.Token, int, error) {
+	for _, stage := range p.stages {
+		name := StageName(stage)
+		if err := ctx.Err(); err != nil {
+			return nil, -1, fmt.Errorf("stage %s: %w", name, err)
+		}
+		var before []tokenizer.Token
+		if p.trace != nil {
+			// Stages may edit their input in place, so copy it first
+			before = joinParagraphs(paras, breaks)
+		}
+		for i := range paras {
+			out, err := stage.Process(ctx, paras[i])
+			if err != nil {
+				return nil, i, fmt.Errorf("stage %s: %w", name, err)
+			}
+			paras[i] = out
+		}
+		if p.trace != nil {
+			p.trace(Stage{Name: name, Processor: stage}, before, joinParagraphs(paras, breaks))
+		}
+	}
+	return paras, -1, nil
+}
+
+// joinParagraphs returns a copy of the tokens of paras with a Space token
+// for each of breaks between them.
+func joinParagraphs(paras [][]tokenizer.Token, breaks []string) []tokenizer.Token {
+	var tokens []tokenizer.Token
+	for i, para := range paras {
+		tokens = append(tokens, para...)
+		if i < len(breaks) {
+			tokens = append(tokens, tokenizer.Token{Type: tokenizer.Space, Value: breaks[i]})
+		}
+	}
+	return tokens
+}
+
+// formatParagraphs formats the paragraphs of text on p.jobs goroutines and
+// joins them in order. The first paragraph to fail cancels the others.
+func (p *Pipeline) formatParagraphs(ctx context.Context, text string) (string, error) {
+	paras, breaks := SplitParagraphs(text)
+	if len(paras) == 1 {
+		return p.formatText(ctx, text)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	out := make([]string, len(paras))
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		failure  error
+	)
+	next := make(chan int)
+	for range min(p.jobs, len(paras)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				s, err := p.formatText(ctx, paras[i].Text)
+				if err != nil {
+					failOnce.Do(func() {
+						failure = fmt.Errorf("paragraph at line %d: %w", paras[i].Line, err)
+						cancel()
+					})
+					continue
+				}
+				out[i] = s
+			}
+		}()
+	}
+
+send:
+	for i := range paras {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	if failure != nil {
+		return "", failure
+	}
+	if err := ctx.Err(); err != nil {
+		// Cancelled before every paragraph was handed out
+		return "", err
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	for i, s := range out {
+		b.WriteString(s)
+		if i < len(breaks) {
+			b.WriteString(breaks[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -1,34 +1,307 @@
 package pipeline
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"go-reloaded/pkg/plugin"
 	"go-reloaded/pkg/processors"
 	"go-reloaded/pkg/tokenizer"
 )
 
 type Pipeline struct {
-	stages []Processor
+	stages    []Processor
+	trace     Trace
+	jobs      int
+	protect   []*regexp.Regexp
+	tokenizer tokenizer.Tokenizer
+}
+
+// Trace is called after each stage with copies of the tokens the stage
+// received and returned, which it may keep.
+type Trace func(stage Stage, before, after []tokenizer.Token)
+
+// Processor is one stage of the pipeline. It is defined in the plugin
+// package so that stages can be written outside this module.
+type Processor = plugin.Processor
+
+// Options configures the stages built by NewWithOptions.
+type Options struct {
+	// Locale selects the punctuation spacing table; empty means English.
+	Locale string
+	// DashStyle selects the spacing around em dashes; empty keeps it as typed.
+	DashStyle processors.DashStyle
+	// SentenceCase capitalizes the first word of every sentence.
+	SentenceCase bool
+	// Stages names the stages to run, from StageNames; they always run in
+	// the standard order. Empty means every built-in stage but "sentence",
+	// which SentenceCase also turns on.
+	Stages []string
+	// Plugins names registered plugin stages to run on top of Stages.
+	Plugins []string
+	// ArticleExceptions are words after which "a" is never changed to "an",
+	// e.g. "one" or "university".
+	ArticleExceptions []string
+	// Protected are regular expressions for text the stages must not
+	// change, on top of what the tokenizer already protects.
+	Protected []string
+	// Macros are user-defined markers, keyed by name in lower case. The
+	// "macro" stage that expands them only runs if there are any.
+	Macros map[string]processors.Macro
+	// Commands are external commands that run as stages, placed among the
+	// others by their constraints like plugins.
+	Commands []Command
+	// Trace, if set, observes the tokens around every stage.
+	Trace Trace
+	// Jobs, if positive, makes Format format up to Jobs paragraphs at
+	// once instead of one after the other. The result is the same for any
+	// number of jobs, zero included. It cannot be combined with Trace.
+	Jobs int
+}
+
+// Command is an external command stage. Its name must not be taken by a
+// built-in or registered stage.
+type Command struct {
+	processors.CommandProcessor
+	// Before and After name the stages it must run before and after.
+	Before, After []string
 }
 
-type Processor interface {
-	Process(tokens []tokenizer.Token) []tokenizer.Token
+// StageNames returns the names Options.Stages accepts: the built-in stages
+// in the order they run, then the registered plugins.
+func StageNames() []string {
+	return append(plugin.Builtin(), plugin.Names()...)
 }
 
 func New() *Pipeline {
-	return &Pipeline{
-		stages: []Processor{
-			processors.HexBinProcessor{},
-			processors.CaseProcessor{},
-			processors.ArticleProcessor{},
-			processors.QuoteProcessor{},
-			processors.PunctuationProcessor{},
-		},
+	p, _ := NewWithOptions(Options{})
+	return p
+}
+
+// NewWithOptions builds the default stage sequence configured by opts.
+func NewWithOptions(opts Options) (*Pipeline, error) {
+	table, ok := processors.PunctuationTableFor(opts.Locale)
+	if !ok {
+		return nil, fmt.Errorf("unknown locale %q", opts.Locale)
+	}
+	if !processors.ValidDashStyle(opts.DashStyle) {
+		return nil, fmt.Errorf("unknown dash style %q", opts.DashStyle)
+	}
+	switch {
+	case opts.Jobs < 0:
+		return nil, fmt.Errorf("invalid number of jobs %d", opts.Jobs)
+	case opts.Jobs > 0 && opts.Trace != nil:
+		return nil, errors.New("paragraph jobs cannot be traced")
+	}
+
+	builtin := plugin.Builtin()
+	enabled := make(map[string]bool)
+	var plugins []plugin.Stage
+	for _, name := range append(slices.Clone(opts.Stages), opts.Plugins...) {
+		if enabled[name] {
+			continue
+		}
+		if s, ok := plugin.Lookup(name); ok {
+			plugins = append(plugins, s)
+		} else if !slices.Contains(builtin, name) {
+			return nil, fmt.Errorf("unknown stage %q (want one of: %s)", name, strings.Join(StageNames(), ", "))
+		}
+		enabled[name] = true
+	}
+	commands := make(map[string]processors.CommandProcessor, len(opts.Commands))
+	for _, c := range opts.Commands {
+		_, registered := plugin.Lookup(c.Name)
+		switch {
+		case c.Name == "":
+			return nil, fmt.Errorf("command stage %v has no name", c.Args)
+		case slices.Contains(builtin, c.Name) || registered:
+			return nil, fmt.Errorf("command stage %q has the name of another stage", c.Name)
+		case commands[c.Name].Name != "":
+			return nil, fmt.Errorf("command stage %q is defined twice", c.Name)
+		}
+		commands[c.Name] = c.CommandProcessor
+		plugins = append(plugins, plugin.Stage{Name: c.Name, Before: c.Before, After: c.After})
+	}
+	if len(opts.Stages) == 0 {
+		for _, name := range builtin {
+			enabled[name] = name != "sentence"
+		}
+	}
+	if opts.SentenceCase {
+		enabled["sentence"] = true
+	}
+	for name := range commands {
+		enabled[name] = true
+	}
+
+	// Plugins run where their constraints put them among the built-in
+	// stages, enabled or not; constraints on plugins that are not enabled
+	// do not apply
+	disabledPlugin := func(name string) bool {
+		_, registered := plugin.Lookup(name)
+		return registered && !enabled[name]
+	}
+	for i, s := range plugins {
+		s.Before = slices.DeleteFunc(slices.Clone(s.Before), disabledPlugin)
+		s.After = slices.DeleteFunc(slices.Clone(s.After), disabledPlugin)
+		plugins[i] = s
+	}
+	order, err := plugin.Sequence(builtin, plugins)
+	if err != nil {
+		return nil, err
+	}
+	pluginOpts := plugin.Options{Locale: opts.Locale, DashStyle: opts.DashStyle, SentenceCase: opts.SentenceCase}
+
+	p := &Pipeline{trace: opts.Trace, jobs: opts.Jobs}
+	if len(opts.Macros) > 0 {
+		p.tokenizer.Markers = make(map[string]bool, len(opts.Macros))
+		for name := range opts.Macros {
+			p.tokenizer.Markers[name] = true
+		}
+	}
+	for _, pattern := range opts.Protected {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protected pattern %q: %w", pattern, err)
+		}
+		p.protect = append(p.protect, re)
+	}
+
+	for _, name := range order {
+		if !enabled[name] {
+			continue
+		}
+		if s, ok := plugin.Lookup(name); ok {
+			p.stages = append(p.stages, s.Factory(pluginOpts))
+			continue
+		}
+		if c, ok := commands[name]; ok {
+			p.stages = append(p.stages, c)
+			continue
+		}
+		switch name {
+		case "macro":
+			if len(opts.Macros) > 0 {
+				p.stages = append(p.stages, plugin.Adapt(processors.MacroProcessor{Macros: opts.Macros}))
+			}
+		case "hexbin":
+			p.stages = append(p.stages, plugin.Adapt(processors.HexBinProcessor{}))
+		case "case":
+			p.stages = append(p.stages, plugin.Adapt(processors.CaseProcessor{}))
+		case "article":
+			p.stages = append(p.stages, plugin.Adapt(processors.ArticleProcessor{Exceptions: opts.ArticleExceptions}))
+		case "quote":
+			p.stages = append(p.stages, plugin.Adapt(processors.QuoteProcessor{}))
+		case "dash":
+			p.stages = append(p.stages, plugin.Adapt(processors.DashProcessor{Style: opts.DashStyle}))
+		case "punctuation":
+			p.stages = append(p.stages, plugin.Adapt(processors.PunctuationProcessor{Table: table}))
+		case "sentence":
+			p.stages = append(p.stages, plugin.Adapt(processors.SentenceProcessor{}))
+		}
+	}
+	return p, nil
+}
+
+// Stage is one named step of a pipeline.
+type Stage struct {
+	Name      string
+	Processor Processor
+}
+
+// Stages returns the pipeline's stages in the order they run.
+func (p *Pipeline) Stages() []Stage {
+	stages := make([]Stage, len(p.stages))
+	for i, proc := range p.stages {
+		stages[i] = Stage{Name: StageName(proc), Processor: proc}
+	}
+	return stages
+}
+
+// StageName returns the display name of a processor: the result of its
+// StageName method if it has one, else its type name, e.g.
+// "CaseProcessor". Processors from plugin.Adapt go by the name of the
+// stage they adapt.
+func StageName(proc any) string {
+	if adapted, ok := proc.(interface{ Unwrap() plugin.Simple }); ok {
+		proc = adapted.Unwrap()
+	}
+	if named, ok := proc.(interface{ StageName() string }); ok {
+		return named.StageName()
+	}
+	t := reflect.TypeOf(proc)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// Process runs tokens through every stage in turn, as one paragraph. It
+// stops at the first stage that fails, or once ctx is done, and returns
+// the error prefixed with the name of the stage.
+func (p *Pipeline) Process(ctx context.Context, tokens []tokenizer.Token) ([]tokenizer.Token, error) {
+	paras, _, err := p.process(ctx, [][]tokenizer.Token{tokens}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return paras[0], nil
+}
+
+// Macros returns the names of the pipeline's user-defined markers, sorted.
+func (p *Pipeline) Macros() []string {
+	names := make([]string, 0, len(p.tokenizer.Markers))
+	for name := range p.tokenizer.Markers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Tokenize splits text into tokens like tokenizer.Tokenize, except that
+// it recognizes the pipeline's macros as markers, and text matching one of
+// its protected patterns becomes a single Protected token.
+func (p *Pipeline) Tokenize(text string) []tokenizer.Token {
+	if len(p.protect) == 0 {
+		return p.tokenizer.Tokenize(text)
+	}
+
+	var matches [][]int
+	for _, re := range p.protect {
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			if m[1] > m[0] {
+				matches = append(matches, m)
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i][0] < matches[j][0] })
+
+	var tokens []tokenizer.Token
+	pos := 0
+	for _, m := range matches {
+		if m[0] < pos {
+			continue // overlaps an earlier match
+		}
+		tokens = append(tokens, p.tokenizer.Tokenize(text[pos:m[0]])...)
+		tokens = append(tokens, tokenizer.Token{Type: tokenizer.Protected, Value: text[m[0]:m[1]]})
+		pos = m[1]
 	}
+	return append(tokens, p.tokenizer.Tokenize(text[pos:])...)
 }
 
-func (p *Pipeline) Process(tokens []tokenizer.Token) []tokenizer.Token {
-	result := tokens
-	for _, stage := range p.stages {
-		result = stage.Process(result)
+// Format splits text into paragraphs at blank lines, runs the tokens of
+// each through the pipeline on its own and joins the results. With jobs,
+// up to that many paragraphs are formatted at once; the result is the
+// same. It fails like Process, naming the line of the paragraph that
+// failed if there are several.
+func (p *Pipeline) Format(ctx context.Context, text string) (string, error) {
+	if p.jobs > 0 {
+		return p.formatParagraphs(ctx, text)
 	}
-	return result
+	return p.formatText(ctx, text)
 }
@@ -1,9 +1,16 @@
 package pipeline
 
 import (
+	"context"
+	"errors"
+	"os/exec"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
+	"go-reloaded/pkg/plugin"
+	"go-reloaded/pkg/processors"
 	"go-reloaded/pkg/tokenizer"
 )
 
@@ -33,9 +40,341 @@ func TestPipeline_Process(t *testing.T) {
 	}
 
 	pl := New()
-	got := pl.Process(input)
+	got, err := pl.Process(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
 
 	if !reflect.DeepEqual(got, want) {
 		t.Errorf("Pipeline Process():\n got  %#v\n want %#v", got, want)
 	}
 }
+
+func TestNewWithOptions_Locale(t *testing.T) {
+	pl, err := NewWithOptions(Options{Locale: "fr"})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+
+	got := format(t, pl, "Quoi ?Vraiment !")
+	want := "Quoi\u202F? Vraiment\u202F!"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := NewWithOptions(Options{Locale: "klingon"}); err == nil {
+		t.Error("expected error for unknown locale")
+	}
+}
+
+func TestNewWithOptions_SentenceCase(t *testing.T) {
+	input := "he said: ' this is a honor ' . it was ... amazing !"
+
+	for _, tt := range []struct {
+		enabled bool
+		want    string
+	}{
+		{false, "he said:' this is an honor'. it was... amazing!"},
+		{true, "He said:' this is an honor'. It was... amazing!"},
+	} {
+		pl, err := NewWithOptions(Options{SentenceCase: tt.enabled})
+		if err != nil {
+			t.Fatalf("NewWithOptions() error = %v", err)
+		}
+		if got := format(t, pl, input); got != tt.want {
+			t.Errorf("SentenceCase=%v: got %q, want %q", tt.enabled, got, tt.want)
+		}
+	}
+}
+
+func TestPipeline_Stages(t *testing.T) {
+	pl, err := NewWithOptions(Options{SentenceCase: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+
+	var got []string
+	for _, stage := range pl.Stages() {
+		got = append(got, stage.Name)
+	}
+	want := []string{
+		"HexBinProcessor", "CaseProcessor", "ArticleProcessor", "QuoteProcessor",
+		"DashProcessor", "PunctuationProcessor", "SentenceProcessor",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stages() = %v, want %v", got, want)
+	}
+}
+
+func TestPipeline_Trace(t *testing.T) {
+	var names []string
+	var lastAfter string
+	trace := func(stage Stage, before, after []tokenizer.Token) {
+		names = append(names, stage.Name)
+		lastAfter = ""
+		for _, tok := range after {
+			lastAfter += tok.Value
+		}
+	}
+
+	pl, err := NewWithOptions(Options{Trace: trace})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	got := format(t, pl, "a apple ,ok")
+
+	if len(names) != len(pl.Stages()) {
+		t.Errorf("trace called for %v, want every stage", names)
+	}
+	if lastAfter != got {
+		t.Errorf("last traced output = %q, want %q", lastAfter, got)
+	}
+}
+
+func TestNewWithOptions_Stages(t *testing.T) {
+	tests := []struct {
+		stages []string
+		want   string
+	}{
+		{nil, "an APPLE, 30 items!"},
+		{[]string{"punctuation", "hexbin"}, "a apple (up), 30 items!"},
+		{[]string{"article"}, "an apple (up) , 1E (hex) items !"},
+	}
+	for _, tt := range tests {
+		pl, err := NewWithOptions(Options{Stages: tt.stages})
+		if err != nil {
+			t.Fatalf("NewWithOptions() error = %v", err)
+		}
+		if got := format(t, pl, "a apple (up) , 1E (hex) items !"); got != tt.want {
+			t.Errorf("stages %v: got %q, want %q", tt.stages, got, tt.want)
+		}
+	}
+
+	if _, err := NewWithOptions(Options{Stages: []string{"spelling"}}); err == nil {
+		t.Error("expected error for unknown stage")
+	}
+}
+
+func TestPipeline_Protected(t *testing.T) {
+	pl, err := NewWithOptions(Options{Protected: []string{`TODO\(\w+\)`, `\bv\d+\.\d+ ,`}})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	got := format(t, pl, "TODO(ann) : ship v1.2 ,then rest ,a idea")
+	want := "TODO(ann): ship v1.2 ,then rest, an idea"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := NewWithOptions(Options{Protected: []string{"("}}); err == nil {
+		t.Error("expected error for an invalid pattern")
+	}
+}
+
+func TestPipeline_NumberLists(t *testing.T) {
+	got := format(t, New(), "Pick 1,2 or 3,4,5 from 1,000 items.")
+	want := "Pick 1, 2 or 3, 4, 5 from 1,000 items."
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// shout uppercases every word.
+type shout struct{ locale string }
+
+func (s shout) Process(tokens []tokenizer.Token) []tokenizer.Token {
+	for i := range tokens {
+		if tokens[i].Type == tokenizer.Word {
+			tokens[i].Value = strings.ToUpper(tokens[i].Value) + s.locale
+		}
+	}
+	return tokens
+}
+
+func TestNewWithOptions_Plugins(t *testing.T) {
+	plugin.Register("pipeline-test-shout", func(opts plugin.Options) plugin.Processor {
+		return plugin.Adapt(shout{locale: opts.Locale})
+	}, plugin.Before("article"))
+
+	// The article stage only sees the uppercased words if the plugin runs
+	// first
+	pl, err := NewWithOptions(Options{Plugins: []string{"pipeline-test-shout"}})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if got, want := format(t, pl, "a apple ,ok"), "An APPLE, OK"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var names []string
+	for _, stage := range pl.Stages() {
+		names = append(names, stage.Name)
+	}
+	want := []string{
+		"HexBinProcessor", "CaseProcessor", "shout", "ArticleProcessor", "QuoteProcessor",
+		"DashProcessor", "PunctuationProcessor",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Stages() = %v, want %v", names, want)
+	}
+
+	pl, err = NewWithOptions(Options{Stages: []string{"pipeline-test-shout"}, Locale: "fr"})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if got, want := format(t, pl, "a b"), "Afr Bfr"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if !slices.Contains(StageNames(), "pipeline-test-shout") {
+		t.Errorf("StageNames() = %v, want the plugin listed", StageNames())
+	}
+	if _, err := NewWithOptions(Options{Plugins: []string{"missing"}}); err == nil {
+		t.Error("expected error for an unregistered plugin")
+	}
+}
+
+func TestNewWithOptions_Commands(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell to run commands with")
+	}
+	brands := Command{
+		CommandProcessor: processors.CommandProcessor{Name: "brands", Args: []string{"sh", "-c", "sed 's/IPHONE/iPhone/g'"}},
+		After:            []string{"case"},
+	}
+	pl, err := NewWithOptions(Options{Commands: []Command{brands}})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if got, want := format(t, pl, "my iphone (up) ,ok"), "my iPhone, ok"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := pl.Stages()[2].Name; got != "brands" {
+		t.Errorf("third stage = %q, want brands", got)
+	}
+
+	failing := Command{CommandProcessor: processors.CommandProcessor{Name: "broken", Args: []string{"sh", "-c", "exit 1"}}}
+	pl, err = NewWithOptions(Options{Commands: []Command{failing}})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := pl.Format(context.Background(), "a apple"); err == nil || !strings.HasPrefix(err.Error(), "stage broken: ") {
+		t.Errorf("Format() error = %v, want one naming the stage", err)
+	}
+
+	for _, cmds := range [][]Command{
+		{{CommandProcessor: processors.CommandProcessor{Name: "case"}}},
+		{brands, brands},
+		{{CommandProcessor: processors.CommandProcessor{Name: "x"}, Before: []string{"spelling"}}},
+	} {
+		if _, err := NewWithOptions(Options{Commands: cmds}); err == nil {
+			t.Errorf("NewWithOptions(%v): expected an error", cmds)
+		}
+	}
+}
+
+func TestPipeline_ProcessCancel(t *testing.T) {
+	var ran []string
+	pl, err := NewWithOptions(Options{Trace: func(stage Stage, before, after []tokenizer.Token) {
+		ran = append(ran, stage.Name)
+	}})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = pl.Process(ctx, tokenizer.Tokenize("a apple"))
+	if !errors.Is(err, context.Canceled) || !strings.HasPrefix(err.Error(), "stage HexBinProcessor: ") {
+		t.Errorf("Process() error = %v, want cancellation at the first stage", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("stages %v ran after cancellation", ran)
+	}
+}
+
+// format runs text through pl and fails the test on error.
+func format(t *testing.T, pl *Pipeline, text string) string {
+	t.Helper()
+	got, err := pl.Format(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Format(%q) error = %v", text, err)
+	}
+	return got
+}
+
+func TestPipeline_Jobs(t *testing.T) {
+	paragraphs := []string{
+		"it was a apple (up) ,he said : ' hello world ' !",
+		"1E (hex) items and 10 (bin) more ...the end",
+		"",
+		"  indented ' quote\nover two lines '",
+		"end.",
+		"(cap, 2) no words before",
+		"It was done .",
+		"A apple.",
+	}
+	var b strings.Builder
+	for i := range 40 {
+		b.WriteString(paragraphs[i%len(paragraphs)])
+		b.WriteString([]string{"\n\n", "\n \n\t\n", "\r\n\r\n"}[i%3])
+	}
+	input := b.String()
+
+	serial, err := NewWithOptions(Options{SentenceCase: true})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	want := format(t, serial, input)
+	// Nothing carries over a blank line: the marker does not reach "ok",
+	// and "a" is seen at the start of its paragraph
+	if got := format(t, serial, "a apple ,ok\n\n\nthe (up) end\n\na apple"); got != "An apple, ok\n\n\nTHE end\n\nAn apple" {
+		t.Errorf("paragraphs formatted together: %q", got)
+	}
+
+	for _, jobs := range []int{1, 2, 3, 8, 64} {
+		pl, err := NewWithOptions(Options{SentenceCase: true, Jobs: jobs})
+		if err != nil {
+			t.Fatalf("NewWithOptions() error = %v", err)
+		}
+		if got := format(t, pl, input); got != want {
+			t.Errorf("Jobs=%d: output differs from Jobs=0:\n got  %q\n want %q", jobs, got, want)
+		}
+	}
+
+	if _, err := NewWithOptions(Options{Jobs: -1}); err == nil {
+		t.Error("expected error for negative jobs")
+	}
+	if _, err := NewWithOptions(Options{Jobs: 2, Trace: func(Stage, []tokenizer.Token, []tokenizer.Token) {}}); err == nil {
+		t.Error("expected error for traced jobs")
+	}
+}
+
+func TestPipeline_JobsError(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell to run commands with")
+	}
+	// Fails on the paragraph that mentions "bad" only
+	check := Command{CommandProcessor: processors.CommandProcessor{Name: "check", Args: []string{"sh", "-c", "! grep -q bad && cat"}}}
+	for _, jobs := range []int{0, 4} {
+		pl, err := NewWithOptions(Options{Commands: []Command{check}, Jobs: jobs})
+		if err != nil {
+			t.Fatalf("NewWithOptions() error = %v", err)
+		}
+		_, err = pl.Format(context.Background(), "fine\n\nalso fine\n\nbad one\n\nfine")
+		if err == nil || !strings.HasPrefix(err.Error(), "paragraph at line 5: stage check: ") {
+			t.Errorf("Jobs=%d: Format() error = %v, want the failing paragraph and stage", jobs, err)
+		}
+	}
+
+	pl, err := NewWithOptions(Options{Commands: []Command{check}, Jobs: 4})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := pl.Format(ctx, "a\n\nb"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Format() error = %v, want context.Canceled", err)
+	}
+}
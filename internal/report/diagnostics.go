@@ -0,0 +1,241 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-reloaded/internal/explain"
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/pkg/tokenizer"
+)
+
+// Diagnostic rule IDs.
+const (
+	RuleMalformedMarker    = "malformed-marker"
+	RuleUnmatchedQuote     = "unmatched-quote"
+	RuleInvalidNumber      = "invalid-number"
+	RuleArticleMismatch    = "article-mismatch"
+	RulePunctuationSpacing = "punctuation-spacing"
+	RuleQuoteSpacing       = "quote-spacing"
+	RuleLeftoverMarker     = "leftover-marker"
+)
+
+// Rule describes a kind of diagnostic.
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Rules lists every rule a diagnostic can have, in a stable order.
+var Rules = []Rule{
+	{RuleMalformedMarker, "A parenthesized marker that no stage understands."},
+	{RuleUnmatchedQuote, "A single quote with no closing partner."},
+	{RuleInvalidNumber, "A (hex) or (bin) marker after a word that is not a number in that base."},
+	{RuleArticleMismatch, "An article that does not match the sound of the next word."},
+	{RulePunctuationSpacing, "Missing or extra space around punctuation."},
+	{RuleQuoteSpacing, "Space just inside a pair of quotes."},
+	{RuleLeftoverMarker, "A marker that formatting would apply and remove."},
+}
+
+// stageRules maps the stages Check runs to the rule of the problems they fix.
+var stageRules = map[string]string{
+	"ArticleProcessor":     RuleArticleMismatch,
+	"QuoteProcessor":       RuleQuoteSpacing,
+	"PunctuationProcessor": RulePunctuationSpacing,
+}
+
+// Diagnostic is a problem found in the input. Diagnose reports the ones the
+// formatter cannot fix; Check and Leftovers report ones formatting would fix.
+type Diagnostic struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Position Region `json:"position"`
+}
+
+// validMarker matches the markers the case and number stages understand.
+var validMarker = regexp.MustCompile(`(?i)^\(\s*(?:(?:up|low|cap)(?:\s*,\s*[1-9]\d*)?|hex|bin)\s*\)$`)
+
+// Diagnose looks for problems in the tokens of a file's input: markers
+// that do not parse, (hex) and (bin) markers after something that is not a
+// number, and a quote with no partner. Markers named in macros are valid.
+func Diagnose(tokens []tokenizer.Token, macros ...string) []Diagnostic {
+	var diags []Diagnostic
+	region := func(i int) Region {
+		line, col := explain.Position(tokens, i)
+		endLine, endCol := explain.Position(tokens, i+1)
+		return Region{line, col, endLine, endCol}
+	}
+
+	lastQuote := -1
+	quotes := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.Type == tokenizer.Marker && !isValidMarker(tok.Value, macros):
+			diags = append(diags, Diagnostic{
+				Rule:     RuleMalformedMarker,
+				Severity: "warning",
+				Message:  fmt.Sprintf("malformed marker %s", tok.Value),
+				Position: region(i),
+			})
+
+		case tok.Type == tokenizer.Marker:
+			base, name := numberBase(tok.Value)
+			w := previousWord(tokens, i)
+			if base == 0 || w < 0 {
+				continue
+			}
+			if _, err := strconv.ParseInt(strings.TrimSpace(tokens[w].Value), base, 64); err != nil {
+				diags = append(diags, Diagnostic{
+					Rule:     RuleInvalidNumber,
+					Severity: "warning",
+					Message:  fmt.Sprintf("%q is not a valid %s number", tokens[w].Value, name),
+					Position: region(w),
+				})
+			}
+
+		case tok.Type == tokenizer.Punct && tok.Value == "'":
+			quotes++
+			lastQuote = i
+		}
+	}
+
+	if quotes%2 == 1 {
+		diags = append(diags, Diagnostic{
+			Rule:     RuleUnmatchedQuote,
+			Severity: "warning",
+			Message:  "quote has no closing partner",
+			Position: region(lastQuote),
+		})
+	}
+	return diags
+}
+
+// Check runs the article, quote and punctuation stages among stages on
+// their own over the tokens of a file's input and reports every change one
+// of them would make, positioned in the input.
+func Check(stages []pipeline.Stage, tokens []tokenizer.Token) []Diagnostic {
+	var diags []Diagnostic
+	seen := make(map[Region]bool)
+	for _, stage := range stages {
+		rule, ok := stageRules[stage.Name]
+		if !ok {
+			continue
+		}
+		// Stages may edit their input in place, so give each its own copy.
+		// The stages checked are built in and only fail on cancellation.
+		after, err := stage.Processor.Process(context.Background(), append([]tokenizer.Token(nil), tokens...))
+		if err != nil {
+			continue
+		}
+		for _, c := range explain.Diff(stage.Name, tokens, after) {
+			// Stages overlap, e.g. both the quote and punctuation stages
+			// trim space inside quotes; the first to run reports it
+			region := Region{c.Line, c.Col, c.EndLine, c.EndCol}
+			if seen[region] {
+				continue
+			}
+			seen[region] = true
+			diags = append(diags, Diagnostic{
+				Rule:     rule,
+				Severity: "warning",
+				Message:  checkMessage(c),
+				Position: region,
+			})
+		}
+	}
+	return diags
+}
+
+// Paragraphs runs find over the tokens of each paragraph of text, which
+// pl formats on its own, and returns what it found positioned in text.
+func Paragraphs(pl *pipeline.Pipeline, text string, find func(tokens []tokenizer.Token) []Diagnostic) []Diagnostic {
+	paras, _ := pipeline.SplitParagraphs(text)
+	var diags []Diagnostic
+	for _, para := range paras {
+		// Every paragraph but the first starts a line, so only lines move
+		for _, d := range find(pl.Tokenize(para.Text)) {
+			d.Position.Line += para.Line - 1
+			d.Position.EndLine += para.Line - 1
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+// Leftovers reports every valid marker in tokens, macros included.
+// Formatting consumes markers, so in text that should already be formatted
+// they were left behind.
+func Leftovers(tokens []tokenizer.Token, macros ...string) []Diagnostic {
+	var diags []Diagnostic
+	for i, tok := range tokens {
+		if tok.Type != tokenizer.Marker || !isValidMarker(tok.Value, macros) {
+			continue
+		}
+		line, col := explain.Position(tokens, i)
+		endLine, endCol := explain.Position(tokens, i+1)
+		diags = append(diags, Diagnostic{
+			Rule:     RuleLeftoverMarker,
+			Severity: "warning",
+			Message:  fmt.Sprintf("marker %s is left in the text; formatting would apply it", tok.Value),
+			Position: Region{line, col, endLine, endCol},
+		})
+	}
+	return diags
+}
+
+// checkMessage says what is wrong with the text a stage changed.
+func checkMessage(c explain.Change) string {
+	switch {
+	case strings.TrimSpace(c.Original+c.New) != "":
+		return fmt.Sprintf("%q should be %q", c.Original, c.New)
+	case c.New == "":
+		return "extra space"
+	case c.Original == "":
+		return "missing space"
+	}
+	return fmt.Sprintf("%q should be %q", c.Original, c.New)
+}
+
+// isValidMarker reports whether marker is a built-in marker or one of
+// macros, with an optional positive count.
+func isValidMarker(marker string, macros []string) bool {
+	if validMarker.MatchString(marker) {
+		return true
+	}
+	name, arg := tokenizer.MarkerName(marker)
+	if !slices.Contains(macros, name) {
+		return false
+	}
+	n, err := strconv.Atoi(arg)
+	return arg == "" || err == nil && n > 0
+}
+
+// numberBase returns the base and its name for a (hex) or (bin) marker, or
+// 0 for any other marker.
+func numberBase(marker string) (int, string) {
+	switch strings.ToLower(strings.ReplaceAll(marker, " ", "")) {
+	case "(hex)":
+		return 16, "hexadecimal"
+	case "(bin)":
+		return 2, "binary"
+	}
+	return 0, ""
+}
+
+// previousWord returns the index of the word right before tokens[i],
+// skipping one space, or -1 if there is none.
+func previousWord(tokens []tokenizer.Token, i int) int {
+	j := i - 1
+	if j >= 0 && tokens[j].Type == tokenizer.Space {
+		j--
+	}
+	if j >= 0 && tokens[j].Type == tokenizer.Word {
+		return j
+	}
+	return -1
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"go-reloaded/internal/explain"
+	"go-reloaded/internal/formats"
+)
+
+// Locator moves positions found in the runs of prose of a document, which
+// count from the start of each run, to the document itself.
+type Locator struct {
+	src   string
+	lines []int // byte offset at which each line starts
+}
+
+// NewLocator returns a Locator for the document src.
+func NewLocator(src string) *Locator {
+	return &Locator{src: src, lines: lineStarts(src)}
+}
+
+// Diagnostics moves diags, found in the text of run, to the document.
+func (l *Locator) Diagnostics(run formats.Run, diags []Diagnostic) []Diagnostic {
+	lines := lineStarts(run.Text)
+	for i := range diags {
+		diags[i].Position = l.region(run, lines, diags[i].Position)
+	}
+	return diags
+}
+
+// Changes moves changes, made to the text of run, to the document.
+func (l *Locator) Changes(run formats.Run, changes []explain.Change) []explain.Change {
+	lines := lineStarts(run.Text)
+	for i, c := range changes {
+		r := l.region(run, lines, Region{c.Line, c.Col, c.EndLine, c.EndCol})
+		changes[i].Line, changes[i].Col, changes[i].EndLine, changes[i].EndCol = r.Line, r.Column, r.EndLine, r.EndColumn
+	}
+	return changes
+}
+
+func (l *Locator) region(run formats.Run, lines []int, r Region) Region {
+	line, col := l.position(run.Offset(offsetAt(run.Text, lines, r.Line, r.Column)))
+	endLine, endCol := l.position(run.Offset(offsetAt(run.Text, lines, r.EndLine, r.EndColumn)))
+	return Region{line, col, endLine, endCol}
+}
+
+// position returns the line and column of byte offset off in the document.
+func (l *Locator) position(off int) (int, int) {
+	line := sort.Search(len(l.lines), func(i int) bool { return l.lines[i] > off }) - 1
+	return line + 1, utf8.RuneCountInString(l.src[l.lines[line]:off]) + 1
+}
+
+// lineStarts returns the byte offset at which each line of s starts.
+func lineStarts(s string) []int {
+	lines := []int{0}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+// offsetAt returns the byte offset in s, whose lines start at lines, of
+// line and col as explain.Position counts them, clamped to s.
+func offsetAt(s string, lines []int, line, col int) int {
+	if line > len(lines) {
+		return len(s)
+	}
+	off := lines[max(line, 1)-1]
+	for n := 1; n < col && off < len(s) && s[off] != '\n'; n++ {
+		_, size := utf8.DecodeRuneInString(s[off:])
+		off += size
+	}
+	return off
+}
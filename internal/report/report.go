@@ -0,0 +1,128 @@
+// Package report describes what a formatting run did to each file, for
+// tools that would rather read results than diff files.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"go-reloaded/internal/explain"
+)
+
+// Report holds the results for every file of a run.
+type Report struct {
+	Files []File `json:"files"`
+}
+
+// File is the result of formatting one file.
+type File struct {
+	Path        string       `json:"path"`
+	Output      string       `json:"output,omitempty"`
+	Changes     []Change     `json:"changes"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Counts      Counts       `json:"counts"`
+}
+
+// Change is one transformation a stage applied.
+type Change struct {
+	Stage    string `json:"stage"`
+	Kind     string `json:"kind"`
+	Marker   string `json:"marker,omitempty"`
+	Original string `json:"original"`
+	New      string `json:"new"`
+	Position Region `json:"position"`
+}
+
+// Region locates text by line and column, both from 1. Columns count
+// runes and the end is exclusive.
+type Region struct {
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
+}
+
+// Counts summarizes a file's changes and diagnostics.
+type Counts struct {
+	Changes     int            `json:"changes"`
+	ByStage     map[string]int `json:"byStage"`
+	Diagnostics int            `json:"diagnostics"`
+	ByRule      map[string]int `json:"byRule"`
+}
+
+// NewFile builds the report for one file from the changes recorded while
+// formatting it and the diagnostics found in its input, which it sorts by
+// position.
+func NewFile(path, output string, changes []explain.Change, diags []Diagnostic) File {
+	f := File{
+		Path:        path,
+		Output:      output,
+		Changes:     make([]Change, 0, len(changes)),
+		Diagnostics: diags,
+		Counts: Counts{
+			Changes:     len(changes),
+			ByStage:     make(map[string]int),
+			Diagnostics: len(diags),
+			ByRule:      make(map[string]int),
+		},
+	}
+	if f.Diagnostics == nil {
+		f.Diagnostics = []Diagnostic{}
+	}
+
+	for _, c := range changes {
+		f.Changes = append(f.Changes, Change{
+			Stage:    c.Stage,
+			Kind:     c.Kind,
+			Marker:   c.Marker,
+			Original: c.Original,
+			New:      c.New,
+			Position: Region{c.Line, c.Col, c.EndLine, c.EndCol},
+		})
+		f.Counts.ByStage[c.Stage]++
+	}
+	sort.SliceStable(f.Diagnostics, func(i, j int) bool {
+		a, b := f.Diagnostics[i].Position, f.Diagnostics[j].Position
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	for _, d := range diags {
+		f.Counts.ByRule[d.Rule]++
+	}
+	return f
+}
+
+// Formats lists the report formats Write accepts.
+func Formats() []string {
+	names := make([]string, 0, len(writers))
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var writers = map[string]func(io.Writer, Report) error{
+	"json":  writeJSON,
+	"sarif": writeSARIF,
+}
+
+// Write encodes r to w in the named format.
+func Write(w io.Writer, format string, r Report) error {
+	write, ok := writers[format]
+	if !ok {
+		return fmt.Errorf("unknown report format %q", format)
+	}
+	return write(w, r)
+}
+
+func writeJSON(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
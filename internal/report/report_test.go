@@ -0,0 +1,173 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"go-reloaded/internal/explain"
+	"go-reloaded/internal/pipeline"
+	"go-reloaded/pkg/tokenizer"
+)
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Diagnostic
+	}{
+		{
+			name:  "clean",
+			input: "it (cap, 2) was 1F (hex) and 101 (bin) ' ok '",
+			want:  nil,
+		},
+		{
+			name:  "malformed markers",
+			input: "go (upx) now (cap, x)",
+			want: []Diagnostic{
+				{RuleMalformedMarker, "warning", "malformed marker (upx)", Region{1, 4, 1, 9}},
+				{RuleMalformedMarker, "warning", "malformed marker (cap, x)", Region{1, 14, 1, 22}},
+			},
+		},
+		{
+			name:  "invalid numbers",
+			input: "zz (hex) and 12 (bin)",
+			want: []Diagnostic{
+				{RuleInvalidNumber, "warning", `"zz" is not a valid hexadecimal number`, Region{1, 1, 1, 3}},
+				{RuleInvalidNumber, "warning", `"12" is not a valid binary number`, Region{1, 14, 1, 16}},
+			},
+		},
+		{
+			name:  "unmatched quote",
+			input: "' one '\nand ' two",
+			want: []Diagnostic{
+				{RuleUnmatchedQuote, "warning", "quote has no closing partner", Region{2, 5, 2, 6}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diagnose(tokenizer.Tokenize(tt.input))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diagnose():\n got  %+v\n want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	input := "a apple (up) ,ok"
+	var rec explain.Recorder
+	pl, err := pipeline.NewWithOptions(pipeline.Options{Trace: rec.Trace})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := pl.Process(context.Background(), tokenizer.Tokenize(input)); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	file := NewFile("in.txt", "out.txt", rec.Changes, Diagnose(tokenizer.Tokenize(input)))
+	var buf bytes.Buffer
+	if err := Write(&buf, "json", Report{Files: []File{file}}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("report is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(got.Files))
+	}
+	f := got.Files[0]
+	if f.Path != "in.txt" || f.Counts.Changes != len(f.Changes) || f.Counts.ByStage["CaseProcessor"] != 1 {
+		t.Errorf("unexpected report: %+v", f)
+	}
+	want := Change{Stage: "CaseProcessor", Kind: explain.KindMarker, Marker: "(up)",
+		Original: "apple (up)", New: "APPLE ", Position: Region{1, 3, 1, 13}}
+	if !reflect.DeepEqual(f.Changes[0], want) {
+		t.Errorf("first change = %+v, want %+v", f.Changes[0], want)
+	}
+	if f.Diagnostics == nil {
+		t.Error("diagnostics should be an empty list, not null")
+	}
+
+	if err := Write(&buf, "xml", Report{}); err == nil {
+		t.Error("expected error for unknown report format")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tokens := tokenizer.Tokenize("a apple ,ok\nfine .")
+	got := Check(pipeline.New().Stages(), tokens)
+	want := []Diagnostic{
+		{RuleArticleMismatch, "warning", `"a" should be "an"`, Region{1, 1, 1, 2}},
+		{RulePunctuationSpacing, "warning", "extra space", Region{1, 8, 1, 9}},
+		{RulePunctuationSpacing, "warning", "missing space", Region{1, 10, 1, 10}},
+		{RulePunctuationSpacing, "warning", "extra space", Region{2, 5, 2, 6}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check():\n got  %+v\n want %+v", got, want)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	input := "a apple (upx)"
+	tokens := tokenizer.Tokenize(input)
+	diags := append(Diagnose(tokens), Check(pipeline.New().Stages(), tokens)...)
+	file := NewFile("docs/in.txt", "", nil, diags)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "sarif", Report{Files: []File{file}}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var got sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("report is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if got.Version != "2.1.0" || len(got.Runs) != 1 {
+		t.Fatalf("unexpected log: %+v", got)
+	}
+	run := got.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(Rules) {
+		t.Errorf("got %d rules, want %d", len(run.Tool.Driver.Rules), len(Rules))
+	}
+
+	var ids []string
+	for _, res := range run.Results {
+		ids = append(ids, res.RuleID)
+		if rule := run.Tool.Driver.Rules[res.RuleIndex]; rule.ID != res.RuleID {
+			t.Errorf("result %s points at rule %s", res.RuleID, rule.ID)
+		}
+	}
+	if want := []string{RuleArticleMismatch, RuleMalformedMarker}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("results = %v, want %v", ids, want)
+	}
+
+	loc := run.Results[1].Locations[0].PhysicalLocation
+	wantLoc := sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: "docs/in.txt"},
+		Region:           sarifRegion{StartLine: 1, StartColumn: 9, EndLine: 1, EndColumn: 14},
+	}
+	if loc != wantLoc {
+		t.Errorf("location = %+v, want %+v", loc, wantLoc)
+	}
+}
+
+func TestDiagnose_Macros(t *testing.T) {
+	tok := tokenizer.Tokenizer{Markers: map[string]bool{"sig": true}}
+	tokens := tok.Tokenize("(sig) (SIG, 2) (sig, x)")
+	want := []Diagnostic{
+		{RuleMalformedMarker, "warning", "malformed marker (sig, x)", Region{1, 16, 1, 24}},
+	}
+	if got := Diagnose(tokens, "sig"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diagnose():\n got  %+v\n want %+v", got, want)
+	}
+	if got := Leftovers(tokens, "sig"); len(got) != 2 {
+		t.Errorf("Leftovers() = %+v, want the two valid markers", got)
+	}
+}
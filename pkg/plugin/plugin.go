@@ -0,0 +1,268 @@
+// Package plugin lets other packages add stages to the formatting
+// pipeline. A package registers its stage in an init function:
+//
+//	func init() {
+//		plugin.Register("brands", func(plugin.Options) plugin.Processor {
+//			return plugin.Adapt(BrandProcessor{})
+//		}, plugin.After("case"), plugin.Before("punctuation"))
+//	}
+//
+// A registered stage only runs when it is enabled by name, from the
+// --plugins flag or the configuration file.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"go-reloaded/pkg/processors"
+	"go-reloaded/pkg/tokenizer"
+)
+
+// Processor is one stage of the pipeline. It may edit tokens in place and
+// returns the tokens for the next stage. It should give up with ctx.Err()
+// once ctx is done. A pipeline may run a stage on several paragraphs at
+// once, so it must be safe for concurrent use.
+type Processor interface {
+	Process(ctx context.Context, tokens []tokenizer.Token) ([]tokenizer.Token, error)
+}
+
+// Simple is a stage that cannot fail, such as the built-in ones in package
+// processors. Adapt makes it a Processor.
+type Simple interface {
+	Process(tokens []tokenizer.Token) []tokenizer.Token
+}
+
+// Adapt returns a Processor that runs s unless ctx is already done.
+func Adapt(s Simple) Processor {
+	return adapter{s}
+}
+
+type adapter struct {
+	s Simple
+}
+
+func (a adapter) Process(ctx context.Context, tokens []tokenizer.Token) ([]tokenizer.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.s.Process(tokens), nil
+}
+
+// Unwrap returns the stage Adapt was given.
+func (a adapter) Unwrap() Simple {
+	return a.s
+}
+
+// Options are the pipeline settings a factory may take into account.
+type Options struct {
+	// Locale is the locale of the text, e.g. "fr"; empty means English.
+	Locale string
+	// DashStyle is the spacing chosen around em dashes.
+	DashStyle processors.DashStyle
+	// SentenceCase is set when sentences get capitalized.
+	SentenceCase bool
+}
+
+// Factory builds a stage for a pipeline.
+type Factory func(Options) Processor
+
+// Order constrains where a stage runs relative to another stage, built in
+// or registered.
+type Order struct {
+	before bool
+	stage  string
+}
+
+// Before makes a stage run before the named stage.
+func Before(stage string) Order { return Order{before: true, stage: stage} }
+
+// After makes a stage run after the named stage.
+func After(stage string) Order { return Order{stage: stage} }
+
+// Stage is a registered stage.
+type Stage struct {
+	Name    string
+	Factory Factory
+	// Before and After name the stages it must run before and after.
+	Before, After []string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Stage{}
+)
+
+// builtin holds the names of the built-in stages in the order they run.
+// Plugins may order themselves against them but not take their names.
+var builtin = []string{"macro", "hexbin", "case", "article", "quote", "dash", "punctuation", "sentence"}
+
+// Builtin returns the names of the built-in stages in the order they run.
+func Builtin() []string {
+	return slices.Clone(builtin)
+}
+
+// Register makes a stage available under name. It panics if name is
+// empty, taken by a built-in stage or already registered, or if factory is
+// nil, as these are programming errors.
+func Register(name string, factory Factory, order ...Order) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" || factory == nil {
+		panic("plugin: Register needs a name and a factory")
+	}
+	if slices.Contains(builtin, name) {
+		panic(fmt.Sprintf("plugin: %q is a built-in stage", name))
+	}
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("plugin: Register called twice for %q", name))
+	}
+
+	s := Stage{Name: name, Factory: factory}
+	for _, o := range order {
+		if o.before {
+			s.Before = append(s.Before, o.stage)
+		} else {
+			s.After = append(s.After, o.stage)
+		}
+	}
+	registry[name] = s
+}
+
+// Lookup returns the stage registered under name.
+func Lookup(name string) (Stage, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	s, ok := registry[name]
+	return s, ok
+}
+
+// Names returns the names of the registered stages, sorted.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sequence places stages among the stages named in order, which are
+// already in the sequence they run, and returns the combined sequence. The
+// constraints of all stages are sorted together: a stage that must follow
+// others goes right after the last of them, the stages in order keep their
+// sequence, any other stage goes at the end, and a stage that must precede
+// another goes right before it unless it is placed earlier already. It fails
+// if a constraint names a stage that is not there or the constraints form a
+// cycle.
+func Sequence(order []string, stages []Stage) ([]string, error) {
+	known := make(map[string]bool, len(order)+len(stages))
+	for _, name := range order {
+		known[name] = true
+	}
+	for _, s := range stages {
+		known[s.Name] = true
+	}
+
+	g := graph{prev: make(map[string][]string), placed: make(map[string]bool)}
+	for i := 1; i < len(order); i++ {
+		g.edge(order[i-1], order[i])
+	}
+	for _, s := range stages {
+		for _, name := range s.After {
+			if !known[name] {
+				return nil, fmt.Errorf("stage %q runs after unknown stage %q", s.Name, name)
+			}
+			g.edge(name, s.Name)
+		}
+		for _, name := range s.Before {
+			if !known[name] {
+				return nil, fmt.Errorf("stage %q runs before unknown stage %q", s.Name, name)
+			}
+			g.edge(s.Name, name)
+		}
+	}
+
+	seq := make([]string, 0, len(known))
+	for len(seq) < len(known) {
+		name := g.runnable(g.wanted(order, stages), make(map[string]bool))
+		if name == "" {
+			for _, s := range stages {
+				if !g.placed[s.Name] {
+					name = s.Name
+					break
+				}
+			}
+			return nil, fmt.Errorf("stage %q is ordered in a cycle", name)
+		}
+		seq = append(seq, name)
+		g.placed[name] = true
+	}
+	return seq, nil
+}
+
+// graph holds the constraints between stages while Sequence sorts them.
+type graph struct {
+	prev   map[string][]string // the stages each stage must run after
+	placed map[string]bool
+}
+
+func (g graph) edge(from, to string) {
+	g.prev[to] = append(g.prev[to], from)
+}
+
+// wanted returns the stage that should run next: the first stage whose
+// stages to follow have all run, or else the next stage in order, or else
+// the first stage left.
+func (g graph) wanted(order []string, stages []Stage) string {
+	for _, s := range stages {
+		if len(s.After) > 0 && !g.placed[s.Name] && g.allPlaced(s.After) {
+			return s.Name
+		}
+	}
+	for _, name := range order {
+		if !g.placed[name] {
+			return name
+		}
+	}
+	for _, s := range stages {
+		if !g.placed[s.Name] {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// runnable returns name if every stage it must run after has run, or else
+// such a stage among those name waits on. It returns "" if there is none,
+// which means name waits on itself.
+func (g graph) runnable(name string, seen map[string]bool) string {
+	if g.placed[name] || seen[name] {
+		return ""
+	}
+	seen[name] = true
+	if g.allPlaced(g.prev[name]) {
+		return name
+	}
+	for _, p := range g.prev[name] {
+		if r := g.runnable(p, seen); r != "" {
+			return r
+		}
+	}
+	return ""
+}
+
+func (g graph) allPlaced(names []string) bool {
+	for _, name := range names {
+		if !g.placed[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+type nop struct{}
+
+func (nop) Process(tokens []tokenizer.Token) []tokenizer.Token { return tokens }
+
+func TestSequence(t *testing.T) {
+	base := []string{"case", "article", "punctuation"}
+	tests := []struct {
+		name    string
+		stages  []Stage
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "no constraints",
+			stages: []Stage{{Name: "x"}},
+			want:   []string{"case", "article", "punctuation", "x"},
+		},
+		{
+			name:   "after",
+			stages: []Stage{{Name: "x", After: []string{"case"}}},
+			want:   []string{"case", "x", "article", "punctuation"},
+		},
+		{
+			name:   "before",
+			stages: []Stage{{Name: "x", Before: []string{"punctuation", "article"}}},
+			want:   []string{"case", "x", "article", "punctuation"},
+		},
+		{
+			name:   "between",
+			stages: []Stage{{Name: "x", After: []string{"case"}, Before: []string{"punctuation"}}},
+			want:   []string{"case", "x", "article", "punctuation"},
+		},
+		{
+			name: "on another plugin",
+			stages: []Stage{
+				{Name: "y", After: []string{"x"}},
+				{Name: "x", Before: []string{"article"}},
+			},
+			want: []string{"case", "x", "y", "article", "punctuation"},
+		},
+		{
+			name: "before a plugin that comes after it",
+			stages: []Stage{
+				{Name: "a", Before: []string{"b"}},
+				{Name: "b", After: []string{"a"}},
+			},
+			want: []string{"case", "article", "punctuation", "a", "b"},
+		},
+		{
+			name: "before a plugin placed among the built-in stages",
+			stages: []Stage{
+				{Name: "a", Before: []string{"b"}},
+				{Name: "b", After: []string{"case"}},
+			},
+			want: []string{"case", "a", "b", "article", "punctuation"},
+		},
+		{
+			name:    "impossible",
+			stages:  []Stage{{Name: "x", After: []string{"punctuation"}, Before: []string{"case"}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown stage",
+			stages:  []Stage{{Name: "x", After: []string{"spelling"}}},
+			wantErr: true,
+		},
+		{
+			name: "cycle",
+			stages: []Stage{
+				{Name: "x", After: []string{"y"}},
+				{Name: "y", After: []string{"x"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sequence(base, tt.stages)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Sequence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sequence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(Options) Processor { return Adapt(nop{}) }
+	Register("plugin-test", factory, Before("punctuation"), After("case"))
+
+	s, ok := Lookup("plugin-test")
+	if !ok {
+		t.Fatal("Lookup() did not find the registered stage")
+	}
+	if !reflect.DeepEqual(s.Before, []string{"punctuation"}) || !reflect.DeepEqual(s.After, []string{"case"}) {
+		t.Errorf("Lookup() = %+v", s)
+	}
+
+	for name, register := range map[string]func(){
+		"duplicate": func() { Register("plugin-test", factory) },
+		"builtin":   func() { Register("case", factory) },
+		"empty":     func() { Register("", factory) },
+		"nil":       func() { Register("plugin-nil", nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Register() did not panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	p := Adapt(nop{})
+	tokens := tokenizer.Tokenize("a b")
+	got, err := p.Process(context.Background(), tokens)
+	if err != nil || !reflect.DeepEqual(got, tokens) {
+		t.Errorf("Process() = %v, %v; want the tokens", got, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Process(ctx, tokens); !errors.Is(err, context.Canceled) {
+		t.Errorf("Process() error = %v, want context.Canceled", err)
+	}
+}
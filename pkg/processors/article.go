@@ -6,7 +6,11 @@ import (
 	"go-reloaded/pkg/tokenizer"
 )
 
-type ArticleProcessor struct{}
+type ArticleProcessor struct {
+	// Exceptions are words, matched without regard to case, after which
+	// "a" is left alone even though they start with a vowel or h.
+	Exceptions []string
+}
 
 func (p ArticleProcessor) Process(tokens []tokenizer.Token) []tokenizer.Token {
 	if len(tokens) < 2 {
@@ -31,7 +35,7 @@ func (p ArticleProcessor) Process(tokens []tokenizer.Token) []tokenizer.Token {
 
 			if j < len(tokens) && tokens[j].Type == tokenizer.Word {
 				nextWord := tokens[j].Value
-				if startsWithVowelOrH(nextWord) {
+				if startsWithVowelOrH(nextWord) && !p.isException(nextWord) {
 					if tok.Value == "A" {
 						tok.Value = "An"
 					} else {
@@ -54,3 +58,12 @@ func startsWithVowelOrH(s string) bool {
 	r := strings.ToLower(string([]rune(s)[0]))
 	return strings.ContainsAny(r, "aeiouh")
 }
+
+func (p ArticleProcessor) isException(word string) bool {
+	for _, e := range p.Exceptions {
+		if strings.EqualFold(word, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -79,3 +79,28 @@ func TestArticleProcessor_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestArticleProcessor_SkipsProtected(t *testing.T) {
+	input := []tokenizer.Token{
+		{Type: tokenizer.Word, Value: "a"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Protected, Value: "`interface`"},
+	}
+	got := ArticleProcessor{}.Process(input)
+	if !reflect.DeepEqual(got, input) {
+		t.Errorf("got %#v, want %#v", got, input)
+	}
+}
+
+func TestArticleProcessor_Exceptions(t *testing.T) {
+	p := ArticleProcessor{Exceptions: []string{"university", "one"}}
+	input := tokenizer.Tokenize("a University, a one and a apple")
+	var got string
+	for _, tok := range p.Process(input) {
+		got += tok.Value
+	}
+	want := "a University, a one and an apple"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -69,3 +69,23 @@ func TestCaseProcessor_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestCaseProcessor_SkipsProtected(t *testing.T) {
+	input := []tokenizer.Token{
+		{Type: tokenizer.Word, Value: "call"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Protected, Value: "`init`"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Marker, Value: "(up)"},
+	}
+	want := []tokenizer.Token{
+		{Type: tokenizer.Word, Value: "CALL"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Protected, Value: "`init`"},
+		{Type: tokenizer.Space, Value: " "},
+	}
+	got := CaseProcessor{}.Process(input)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
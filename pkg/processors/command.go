@@ -0,0 +1,131 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// DefaultCommandTimeout bounds a CommandProcessor run with no Timeout.
+const DefaultCommandTimeout = 10 * time.Second
+
+// CommandProcessor runs an external command as a stage. The command reads
+// the text on standard input and writes the new text on standard output,
+// or with JSON set it reads and writes a token stream:
+//
+//	[{"type": "Word", "value": "hello"}, {"type": "Space", "value": " "}]
+//
+// Text output is tokenized again, so protected text is only safe from a
+// command in JSON mode.
+type CommandProcessor struct {
+	// Name identifies the stage, e.g. "brands".
+	Name string
+	// Args are the command and its arguments. A relative command path is
+	// resolved against Dir.
+	Args []string
+	// Dir is the directory the command runs in; empty means the current one.
+	Dir string
+	// JSON exchanges a token stream instead of text.
+	JSON bool
+	// Timeout bounds a run; zero means DefaultCommandTimeout.
+	Timeout time.Duration
+}
+
+// StageName returns the name of the stage, which is more telling than the
+// type name when several commands run.
+func (p CommandProcessor) StageName() string {
+	return p.Name
+}
+
+// commandToken is a token as a command reads and writes it.
+type commandToken struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Process runs the command over tokens. It fails if the command cannot
+// start, exits with an error, runs out of time or writes a token stream
+// that does not parse. The command is killed once ctx is done.
+func (p CommandProcessor) Process(ctx context.Context, tokens []tokenizer.Token) ([]tokenizer.Token, error) {
+	if len(p.Args) == 0 {
+		return nil, errors.New("no command to run")
+	}
+
+	var input []byte
+	if p.JSON {
+		stream := make([]commandToken, len(tokens))
+		for i, tok := range tokens {
+			stream[i] = commandToken{tokenizer.TypeName(tok.Type), tok.Value}
+		}
+		var err error
+		if input, err = json.Marshal(stream); err != nil {
+			return nil, err
+		}
+	} else {
+		var b strings.Builder
+		for _, tok := range tokens {
+			b.WriteString(tok.Value)
+		}
+		input = []byte(b.String())
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, p.Args[0], p.Args[1:]...)
+	cmd.Dir = p.Dir
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Do not wait forever on children that inherited the output pipes
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if runCtx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("%s timed out after %s", p.Args[0], timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", p.Args[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", p.Args[0], err)
+	}
+
+	if !p.JSON {
+		text := stdout.String()
+		// Most tools end their output with a newline; drop it unless the
+		// input had one too
+		if !bytes.HasSuffix(input, []byte("\n")) {
+			text = strings.TrimSuffix(text, "\n")
+		}
+		return tokenizer.Tokenize(text), nil
+	}
+
+	var stream []commandToken
+	if err := json.Unmarshal(stdout.Bytes(), &stream); err != nil {
+		return nil, fmt.Errorf("invalid token stream from %s: %w", p.Args[0], err)
+	}
+	out := make([]tokenizer.Token, len(stream))
+	for i, tok := range stream {
+		typ, ok := tokenizer.ParseType(tok.Type)
+		if !ok {
+			return nil, fmt.Errorf("invalid token stream from %s: unknown token type %q", p.Args[0], tok.Type)
+		}
+		out[i] = tokenizer.Token{Type: typ, Value: tok.Value}
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+func TestCommandProcessor_Process(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell to run commands with")
+	}
+	sh := func(script string) []string { return []string{"sh", "-c", script} }
+
+	tests := []struct {
+		name    string
+		p       CommandProcessor
+		input   string
+		want    string
+		wantErr string
+	}{
+		{"text", CommandProcessor{Args: sh("sed 's/acme/Acme/g'")}, "an acme ,ok", "an Acme ,ok", ""},
+		{"tokens", CommandProcessor{Args: sh("sed 's/\"Word\",\"value\":\"acme\"/\"Protected\",\"value\":\"ACME\"/'"), JSON: true}, "acme rocks", "ACME rocks", ""},
+		{"exit status", CommandProcessor{Args: sh("echo broken >&2; exit 3")}, "x", "", "exit status 3: broken"},
+		{"timeout", CommandProcessor{Args: sh("exec sleep 5"), Timeout: 50 * time.Millisecond}, "x", "", "timed out after 50ms"},
+		{"bad stream", CommandProcessor{Args: sh("echo '[{\"type\": \"Noun\"}]'"), JSON: true}, "x", "", `unknown token type "Noun"`},
+		{"missing", CommandProcessor{Args: []string{"textfmt-no-such-command"}}, "x", "", "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tt.p.Process(context.Background(), tokenizer.Tokenize(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Process() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if got := joinValues(tokens); got != tt.want {
+				t.Errorf("Process() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandProcessor_Cancel(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell to run commands with")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p := CommandProcessor{Args: []string{"sh", "-c", "exec sleep 5"}}
+	start := time.Now()
+	if _, err := p.Process(ctx, tokenizer.Tokenize("x")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Process() error = %v, want the context's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Process() took %s after cancellation", elapsed)
+	}
+}
+
+func joinValues(tokens []tokenizer.Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(t.Value)
+	}
+	return b.String()
+}
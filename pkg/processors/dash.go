@@ -0,0 +1,87 @@
+package processors
+
+import (
+	"strings"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// DashStyle controls the spacing DashProcessor puts around em dashes.
+type DashStyle string
+
+const (
+	// DashPreserve keeps whatever spacing the input had.
+	DashPreserve DashStyle = ""
+	// DashSpaced puts one space on each side: "word — word".
+	DashSpaced DashStyle = "spaced"
+	// DashUnspaced closes the dash up: "word—word".
+	DashUnspaced DashStyle = "unspaced"
+)
+
+// DashProcessor turns "--" and "---" into an em dash and normalizes the
+// spacing around em dashes. En dashes and hyphenated words are left alone.
+type DashProcessor struct {
+	Style DashStyle
+}
+
+func (p DashProcessor) Process(tokens []tokenizer.Token) []tokenizer.Token {
+	out := make([]tokenizer.Token, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+
+		if tok.Type != tokenizer.Punct || !isEmDash(tok.Value) {
+			out = append(out, tok)
+			continue
+		}
+		tok.Value = "—"
+
+		if p.Style == DashPreserve {
+			out = append(out, tok)
+			continue
+		}
+
+		// Drop the spaces on both sides, keeping line breaks
+		for len(out) > 0 && isInlineSpace(out[len(out)-1]) {
+			out = out[:len(out)-1]
+		}
+		for i+1 < len(tokens) && isInlineSpace(tokens[i+1]) {
+			i++
+		}
+
+		if p.Style == DashSpaced && len(out) > 0 && out[len(out)-1].Type != tokenizer.Space {
+			out = append(out, tokenizer.Token{Type: tokenizer.Space, Value: " "})
+		}
+		out = append(out, tok)
+		if p.Style == DashSpaced && i+1 < len(tokens) && tokens[i+1].Type != tokenizer.Space {
+			out = append(out, tokenizer.Token{Type: tokenizer.Space, Value: " "})
+		}
+	}
+
+	return out
+}
+
+// ValidDashStyle reports whether s names a known dash style.
+func ValidDashStyle(s DashStyle) bool {
+	return s == DashPreserve || s == DashSpaced || s == DashUnspaced
+}
+
+func isEmDash(s string) bool {
+	return s == "--" || s == "---" || s == "—"
+}
+
+func isInlineSpace(tok tokenizer.Token) bool {
+	return tok.Type == tokenizer.Space && !isBreak(tok)
+}
+
+// isBreak reports whether tok is a space that holds a line break, soft or
+// not, or stands for inline markup. Both are layout, so spacing rules
+// leave them alone.
+func isBreak(tok tokenizer.Token) bool {
+	return tok.Type == tokenizer.Space && strings.ContainsAny(tok.Value, "\r\n"+string(tokenizer.SoftBreak)+string(tokenizer.Markup))
+}
+
+// isMarkup reports whether tok stands for inline markup.
+func isMarkup(tok tokenizer.Token) bool {
+	return tok.Type == tokenizer.Space && tok.Value == string(tokenizer.Markup)
+}
@@ -0,0 +1,76 @@
+package processors
+
+import (
+	"reflect"
+	"testing"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+func TestDashProcessor_Process(t *testing.T) {
+	input := []tokenizer.Token{
+		{Type: tokenizer.Word, Value: "wait"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Punct, Value: "--"},
+		{Type: tokenizer.Word, Value: "what"},
+	}
+
+	tests := []struct {
+		name  string
+		style DashStyle
+		want  []tokenizer.Token
+	}{
+		{
+			name:  "preserve spacing",
+			style: DashPreserve,
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "wait"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: "—"},
+				{Type: tokenizer.Word, Value: "what"},
+			},
+		},
+		{
+			name:  "spaced",
+			style: DashSpaced,
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "wait"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: "—"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "what"},
+			},
+		},
+		{
+			name:  "unspaced",
+			style: DashUnspaced,
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "wait"},
+				{Type: tokenizer.Punct, Value: "—"},
+				{Type: tokenizer.Word, Value: "what"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := DashProcessor{Style: tt.style}
+			got := p.Process(append([]tokenizer.Token(nil), input...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s:\n got  %#v\n want %#v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDashProcessor_EnDashUntouched(t *testing.T) {
+	input := []tokenizer.Token{
+		{Type: tokenizer.Word, Value: "1"},
+		{Type: tokenizer.Punct, Value: "–"},
+		{Type: tokenizer.Word, Value: "5"},
+	}
+	got := DashProcessor{Style: DashSpaced}.Process(input)
+	if !reflect.DeepEqual(got, input) {
+		t.Errorf("got %#v, want %#v", got, input)
+	}
+}
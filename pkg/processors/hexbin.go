@@ -50,6 +50,10 @@ func isHexBinMarker(markerValue string) bool {
 
 // Helper that performs the numeric conversion
 func convertIfNeeded(tok tokenizer.Token, marker tokenizer.Token) tokenizer.Token {
+	if tok.Type == tokenizer.Protected {
+		return tok
+	}
+
 	val := strings.ToLower(strings.TrimSpace(marker.Value))
 	word := strings.TrimSpace(tok.Value)
 
@@ -0,0 +1,202 @@
+package processors
+
+import (
+	"strings"
+	"sync"
+)
+
+// Spacing describes the whitespace enforced on one side of a punctuation mark.
+type Spacing struct {
+	// Keep leaves whatever whitespace the input had untouched.
+	Keep bool
+	// Space is the whitespace to enforce; empty removes it.
+	Space string
+}
+
+// PunctRule is the spacing applied around a punctuation mark.
+type PunctRule struct {
+	Before Spacing
+	After  Spacing
+	// Replace, when set, substitutes the mark itself (e.g. full-width forms).
+	Replace string
+}
+
+// PunctuationTable holds the spacing rules of one locale. Rules are looked up
+// by the whole token value first (e.g. "..."), then by its first mark for
+// the space before and by its last mark for the space after.
+type PunctuationTable struct {
+	Locale  string
+	Default PunctRule
+	Rules   map[string]PunctRule
+}
+
+const (
+	nbsp       = "\u00A0"
+	narrowNbsp = "\u202F"
+)
+
+var (
+	noSpace     = Spacing{}
+	oneSpace    = Spacing{Space: " "}
+	keepSpacing = Spacing{Keep: true}
+)
+
+// commonRules apply in every locale unless its table overrides them: opening
+// brackets keep the space before them and trim the one inside, closing
+// brackets behave like any other mark, and dashes are left to DashProcessor.
+var commonRules = map[string]PunctRule{
+	"(": {Before: keepSpacing, After: noSpace},
+	"[": {Before: keepSpacing, After: noSpace},
+	"{": {Before: keepSpacing, After: noSpace},
+	")": {Before: noSpace, After: oneSpace},
+	"]": {Before: noSpace, After: oneSpace},
+	"}": {Before: noSpace, After: oneSpace},
+	"-": {Before: keepSpacing, After: keepSpacing},
+	"—": {Before: keepSpacing, After: keepSpacing},
+	"–": {Before: keepSpacing, After: keepSpacing},
+}
+
+// English is the default table: no space before a mark, one space after.
+var English = &PunctuationTable{
+	Locale:  "en",
+	Default: PunctRule{Before: noSpace, After: oneSpace},
+	Rules: map[string]PunctRule{
+		"«": {Before: keepSpacing, After: noSpace},
+		"»": {Before: noSpace, After: oneSpace},
+	},
+}
+
+// French puts a narrow no-break space before ; ! ?, a no-break space before :
+// and no-break spaces inside « ».
+var French = &PunctuationTable{
+	Locale:  "fr",
+	Default: PunctRule{Before: noSpace, After: oneSpace},
+	Rules: map[string]PunctRule{
+		";": {Before: Spacing{Space: narrowNbsp}, After: oneSpace},
+		"!": {Before: Spacing{Space: narrowNbsp}, After: oneSpace},
+		"?": {Before: Spacing{Space: narrowNbsp}, After: oneSpace},
+		":": {Before: Spacing{Space: nbsp}, After: oneSpace},
+		"«": {Before: keepSpacing, After: Spacing{Space: nbsp}},
+		"»": {Before: Spacing{Space: nbsp}, After: oneSpace},
+	},
+}
+
+// SwissFrench uses narrow no-break spaces before all high marks and inside « ».
+var SwissFrench = &PunctuationTable{
+	Locale:  "fr-CH",
+	Default: PunctRule{Before: noSpace, After: oneSpace},
+	Rules: map[string]PunctRule{
+		";": {Before: Spacing{Space: narrowNbsp}, After: oneSpace},
+		"!": {Before: Spacing{Space: narrowNbsp}, After: oneSpace},
+		"?": {Before: Spacing{Space: narrowNbsp}, After: oneSpace},
+		":": {Before: Spacing{Space: narrowNbsp}, After: oneSpace},
+		"«": {Before: keepSpacing, After: Spacing{Space: narrowNbsp}},
+		"»": {Before: Spacing{Space: narrowNbsp}, After: oneSpace},
+	},
+}
+
+// CJK converts ASCII marks to their full-width forms and removes all
+// spacing around punctuation.
+var CJK = &PunctuationTable{
+	Locale:  "cjk",
+	Default: PunctRule{Before: noSpace, After: noSpace},
+	Rules: map[string]PunctRule{
+		",":   {Replace: "，"},
+		".":   {Replace: "。"},
+		"!":   {Replace: "！"},
+		"?":   {Replace: "？"},
+		":":   {Replace: "："},
+		";":   {Replace: "；"},
+		"...": {Replace: "……"},
+	},
+}
+
+var (
+	localeMu     sync.RWMutex
+	localeTables = map[string]*PunctuationTable{
+		"en":    English,
+		"fr":    French,
+		"fr-ch": SwissFrench,
+		"cjk":   CJK,
+		"zh":    CJK,
+		"ja":    CJK,
+	}
+)
+
+// RegisterPunctuationTable makes t available under its locale name, replacing
+// any table previously registered for that locale.
+func RegisterPunctuationTable(t *PunctuationTable) {
+	localeMu.Lock()
+	defer localeMu.Unlock()
+	localeTables[normalizeLocale(t.Locale)] = t
+}
+
+// PunctuationTableFor returns the table for a locale such as "fr", "fr_CH" or
+// "zh-TW", falling back to the language part when the region is unknown.
+func PunctuationTableFor(locale string) (*PunctuationTable, bool) {
+	localeMu.RLock()
+	defer localeMu.RUnlock()
+
+	name := normalizeLocale(locale)
+	if name == "" {
+		return English, true
+	}
+	if t, ok := localeTables[name]; ok {
+		return t, true
+	}
+	if lang, _, found := strings.Cut(name, "-"); found {
+		t, ok := localeTables[lang]
+		return t, ok
+	}
+	return nil, false
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// before returns the spacing rule for the left side of a punctuation token.
+func (t *PunctuationTable) before(value string) Spacing {
+	if r, ok := t.Rules[value]; ok {
+		return r.Before
+	}
+	return t.lookup(string([]rune(value)[0])).Before
+}
+
+// after returns the spacing rule for the right side of a punctuation token.
+func (t *PunctuationTable) after(value string) Spacing {
+	if r, ok := t.Rules[value]; ok {
+		return r.After
+	}
+	runes := []rune(value)
+	return t.lookup(string(runes[len(runes)-1])).After
+}
+
+// replace substitutes the token, or each of its marks, when the table says so.
+func (t *PunctuationTable) replace(value string) string {
+	if r, ok := t.Rules[value]; ok {
+		if r.Replace != "" {
+			return r.Replace
+		}
+		return value
+	}
+	var b strings.Builder
+	for _, ch := range value {
+		if r, ok := t.Rules[string(ch)]; ok && r.Replace != "" {
+			b.WriteString(r.Replace)
+		} else {
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+func (t *PunctuationTable) lookup(mark string) PunctRule {
+	if r, ok := t.Rules[mark]; ok {
+		return r
+	}
+	if r, ok := commonRules[mark]; ok {
+		return r
+	}
+	return t.Default
+}
@@ -0,0 +1,69 @@
+package processors
+
+import (
+	"regexp"
+	"strconv"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// Macro is a user-defined marker. It either expands to Text or, with a
+// Pattern, rewrites the words before it.
+type Macro struct {
+	// Text replaces the marker, e.g. "™" for (tm).
+	Text string
+	// Pattern and Replace rewrite each of the Words words before the
+	// marker, as regexp.ReplaceAllString does; "(name, n)" rewrites n
+	// words instead. Words defaults to 1.
+	Pattern *regexp.Regexp
+	Replace string
+	Words   int
+}
+
+// MacroProcessor expands user-defined markers. It runs first, so the text
+// a macro produces is formatted like the rest. The tokenizer only produces
+// markers for names it is told about, see tokenizer.Tokenizer.
+type MacroProcessor struct {
+	// Macros are keyed by marker name in lower case, e.g. "sig".
+	Macros map[string]Macro
+}
+
+func (p MacroProcessor) Process(tokens []tokenizer.Token) []tokenizer.Token {
+	out := make([]tokenizer.Token, 0, len(tokens))
+
+	for _, tok := range tokens {
+		if tok.Type != tokenizer.Marker {
+			out = append(out, tok)
+			continue
+		}
+		name, arg := tokenizer.MarkerName(tok.Value)
+		macro, ok := p.Macros[name]
+		if !ok {
+			out = append(out, tok)
+			continue
+		}
+
+		if macro.Pattern == nil {
+			out = append(out, tokenizer.Tokenize(macro.Text)...)
+			continue
+		}
+
+		count := macro.Words
+		if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+			count = n
+		}
+		if count < 1 {
+			count = 1
+		}
+		// Rewrite backwards, like the case markers, and drop the marker
+		for j := len(out) - 1; j >= 0 && count > 0; j-- {
+			if out[j].Type != tokenizer.Word {
+				continue
+			}
+			out[j].Value = macro.Pattern.ReplaceAllString(out[j].Value, macro.Replace)
+			count--
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,39 @@
+package processors
+
+import (
+	"regexp"
+	"testing"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+func TestMacroProcessor_Process(t *testing.T) {
+	p := MacroProcessor{Macros: map[string]Macro{
+		"tm":     {Text: "™"},
+		"sig":    {Text: "Best regards, The Team"},
+		"strike": {Pattern: regexp.MustCompile(`^.*$`), Replace: "~~$0~~"},
+		"ver":    {Pattern: regexp.MustCompile(`^(\d+)$`), Replace: "v$1", Words: 2},
+	}}
+	tok := tokenizer.Tokenizer{Markers: map[string]bool{"tm": true, "sig": true, "strike": true, "ver": true}}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Acme(tm) rocks", "Acme™ rocks"},
+		{"Thanks!\n(sig)", "Thanks!\nBest regards, The Team"},
+		{"it is old news (strike)", "it is old ~~news~~ "},
+		{"it is old news (strike, 2)", "it is ~~old~~ ~~news~~ "},
+		{"pages 1 2 (ver) ok", "pages v1 v2  ok"},
+		{"keep (up) and (hex)", "keep (up) and (hex)"},
+	}
+	for _, tt := range tests {
+		var got string
+		for _, t := range p.Process(tok.Tokenize(tt.input)) {
+			got += t.Value
+		}
+		if got != tt.want {
+			t.Errorf("Process(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
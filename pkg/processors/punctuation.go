@@ -1,33 +1,74 @@
 package processors
 
-import (
-	"go-reloaded/pkg/tokenizer"
-)
+import "go-reloaded/pkg/tokenizer"
 
-type PunctuationProcessor struct{}
+// PunctuationProcessor normalizes the spacing around punctuation according to
+// a locale table. The zero value uses the English rules.
+type PunctuationProcessor struct {
+	Table *PunctuationTable
+}
 
 func (p PunctuationProcessor) Process(tokens []tokenizer.Token) []tokenizer.Token {
+	table := p.Table
+	if table == nil {
+		table = English
+	}
+
 	out := make([]tokenizer.Token, 0, len(tokens))
+	var after Spacing // spacing owed after the last punctuation in out
 
 	for i := 0; i < len(tokens); i++ {
 		tok := tokens[i]
 
 		if tok.Type == tokenizer.Punct {
-			// Remove space before punctuation
-			if len(out) > 0 && out[len(out)-1].Type == tokenizer.Space {
-				out = out[:len(out)-1]
+			before := table.before(tok.Value)
+			if !before.Keep {
+				// Remove space before punctuation
+				if len(out) > 0 && isInlineSpace(out[len(out)-1]) {
+					out = out[:len(out)-1]
+				}
+				if before.Space != "" && len(out) > 0 && out[len(out)-1].Type != tokenizer.Space {
+					out = append(out, tokenizer.Token{Type: tokenizer.Space, Value: before.Space})
+				}
+			} else if opening := !table.after(tok.Value).Keep; opening &&
+				len(out) > 0 && out[len(out)-1].Type == tokenizer.Punct && !after.Keep && after.Space != "" {
+				// Opening marks such as « still owe the previous mark its space
+				out = append(out, tokenizer.Token{Type: tokenizer.Space, Value: after.Space})
 			}
+			after = table.after(tok.Value)
+			tok.Value = table.replace(tok.Value)
 			out = append(out, tok)
 			continue
 		}
 
-		// Add space after punctuation if current token is not space and previous was punctuation
-		if len(out) > 0 && out[len(out)-1].Type == tokenizer.Punct && tok.Type != tokenizer.Space {
-			out = append(out, tokenizer.Token{Type: tokenizer.Space, Value: " "})
+		if len(out) > 0 && out[len(out)-1].Type == tokenizer.Punct && !after.Keep {
+			if isMarkup(tok) {
+				// Markup right before the next word takes the space the
+				// mark owes, as in "wait, <b>what</b>"
+				if after.Space != "" && wordFollows(tokens[i+1:]) {
+					out = append(out, tokenizer.Token{Type: tokenizer.Space, Value: after.Space})
+				}
+			} else if tok.Type == tokenizer.Space {
+				// Line breaks are layout, not spacing
+				if !isBreak(tok) {
+					if after.Space == "" {
+						continue
+					}
+					tok.Value = after.Space
+				}
+			} else if after.Space != "" {
+				// Add space after punctuation if current token is not space
+				out = append(out, tokenizer.Token{Type: tokenizer.Space, Value: after.Space})
+			}
 		}
 
-		// Skip multiple consecutive spaces
-		if tok.Type == tokenizer.Space && len(out) > 0 && out[len(out)-1].Type == tokenizer.Space {
+		// Skip multiple consecutive spaces, keeping a line break among them;
+		// markup takes no room, so the spaces around it are not doubled
+		if tok.Type == tokenizer.Space && len(out) > 0 && out[len(out)-1].Type == tokenizer.Space &&
+			!isMarkup(tok) && !isMarkup(out[len(out)-1]) {
+			if isBreak(tok) && !isBreak(out[len(out)-1]) {
+				out[len(out)-1] = tok
+			}
 			continue
 		}
 
@@ -36,3 +77,14 @@ func (p PunctuationProcessor) Process(tokens []tokenizer.Token) []tokenizer.Toke
 
 	return out
 }
+
+// wordFollows reports whether the first token of tokens that is not markup
+// is other than a space.
+func wordFollows(tokens []tokenizer.Token) bool {
+	for _, tok := range tokens {
+		if !isMarkup(tok) {
+			return tok.Type != tokenizer.Space
+		}
+	}
+	return false
+}
@@ -28,6 +28,58 @@ func TestPunctuationProcessor_Process(t *testing.T) {
 				{Type: tokenizer.Word, Value: "next"},
 			},
 		},
+		{
+			name: "soft line breaks are kept",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "one"},
+				{Type: tokenizer.Punct, Value: ","},
+				{Type: tokenizer.Space, Value: "\u2028"},
+				{Type: tokenizer.Word, Value: "two"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Space, Value: "\u2028"},
+				{Type: tokenizer.Word, Value: "three"},
+				{Type: tokenizer.Space, Value: "\u2028"},
+				{Type: tokenizer.Punct, Value: "."},
+			},
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "one"},
+				{Type: tokenizer.Punct, Value: ","},
+				{Type: tokenizer.Space, Value: "\u2028"},
+				{Type: tokenizer.Word, Value: "two"},
+				{Type: tokenizer.Space, Value: "\u2028"},
+				{Type: tokenizer.Word, Value: "three"},
+				{Type: tokenizer.Space, Value: "\u2028"},
+				{Type: tokenizer.Punct, Value: "."},
+			},
+		},
+		{
+			name: "markup takes no room",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "wait"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: ","},
+				{Type: tokenizer.Space, Value: "\uFFFC"},
+				{Type: tokenizer.Word, Value: "what"},
+				{Type: tokenizer.Space, Value: "\uFFFC"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "now"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Space, Value: "\uFFFC"},
+			},
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "wait"},
+				{Type: tokenizer.Punct, Value: ","},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Space, Value: "\uFFFC"},
+				{Type: tokenizer.Word, Value: "what"},
+				{Type: tokenizer.Space, Value: "\uFFFC"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "now"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Space, Value: "\uFFFC"},
+			},
+		},
 		{
 			name: "grouped punctuation ...",
 			input: []tokenizer.Token{
@@ -72,3 +124,135 @@ func TestPunctuationProcessor_Process(t *testing.T) {
 		})
 	}
 }
+
+func TestPunctuationProcessor_Locales(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		input  []tokenizer.Token
+		want   []tokenizer.Token
+	}{
+		{
+			name:   "french high marks",
+			locale: "fr",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "Vraiment"},
+				{Type: tokenizer.Punct, Value: "?"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "Oui"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: ":"},
+				{Type: tokenizer.Word, Value: "non"},
+			},
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "Vraiment"},
+				{Type: tokenizer.Space, Value: "\u202F"},
+				{Type: tokenizer.Punct, Value: "?"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "Oui"},
+				{Type: tokenizer.Space, Value: "\u00A0"},
+				{Type: tokenizer.Punct, Value: ":"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "non"},
+			},
+		},
+		{
+			name:   "french guillemets",
+			locale: "fr_FR",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "dit"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: "«"},
+				{Type: tokenizer.Word, Value: "salut"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: "»"},
+			},
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "dit"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: "«"},
+				{Type: tokenizer.Space, Value: "\u00A0"},
+				{Type: tokenizer.Word, Value: "salut"},
+				{Type: tokenizer.Space, Value: "\u00A0"},
+				{Type: tokenizer.Punct, Value: "»"},
+			},
+		},
+		{
+			name:   "swiss colon",
+			locale: "fr-CH",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "Note"},
+				{Type: tokenizer.Punct, Value: ":"},
+			},
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "Note"},
+				{Type: tokenizer.Space, Value: "\u202F"},
+				{Type: tokenizer.Punct, Value: ":"},
+			},
+		},
+		{
+			name:   "cjk full-width without spaces",
+			locale: "zh-TW",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "你好"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: ","},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "世界"},
+				{Type: tokenizer.Punct, Value: "..."},
+			},
+			want: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "你好"},
+				{Type: tokenizer.Punct, Value: "，"},
+				{Type: tokenizer.Word, Value: "世界"},
+				{Type: tokenizer.Punct, Value: "……"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, ok := PunctuationTableFor(tt.locale)
+			if !ok {
+				t.Fatalf("no table for locale %q", tt.locale)
+			}
+			p := PunctuationProcessor{Table: table}
+			got := p.Process(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s:\n got  %#v\n want %#v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPunctuationTableFor_Unknown(t *testing.T) {
+	if _, ok := PunctuationTableFor("xx"); ok {
+		t.Error("expected no table for unknown locale")
+	}
+}
+
+func TestPunctuationProcessor_Brackets(t *testing.T) {
+	input := []tokenizer.Token{
+		{Type: tokenizer.Word, Value: "see"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Punct, Value: "("},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Word, Value: "below"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Punct, Value: ")"},
+		{Type: tokenizer.Word, Value: "now"},
+	}
+	want := []tokenizer.Token{
+		{Type: tokenizer.Word, Value: "see"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Punct, Value: "("},
+		{Type: tokenizer.Word, Value: "below"},
+		{Type: tokenizer.Punct, Value: ")"},
+		{Type: tokenizer.Space, Value: " "},
+		{Type: tokenizer.Word, Value: "now"},
+	}
+	got := PunctuationProcessor{}.Process(input)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %#v\nwant %#v", got, want)
+	}
+}
@@ -15,7 +15,7 @@ func (p QuoteProcessor) Process(tokens []tokenizer.Token) []tokenizer.Token {
 		if tok.Type == tokenizer.Punct && (tok.Value == "'" || tok.Value == "\"") {
 			if openQuote == tok.Value {
 				// Closing quote - remove preceding space if any
-				if len(out) > 0 && out[len(out)-1].Type == tokenizer.Space {
+				if len(out) > 0 && isInlineSpace(out[len(out)-1]) {
 					out = out[:len(out)-1]
 				}
 				out = append(out, tok)
@@ -24,7 +24,7 @@ func (p QuoteProcessor) Process(tokens []tokenizer.Token) []tokenizer.Token {
 				// Opening quote
 				out = append(out, tok)
 				// Skip following space if present
-				if i+1 < len(tokens) && tokens[i+1].Type == tokenizer.Space {
+				if i+1 < len(tokens) && isInlineSpace(tokens[i+1]) {
 					i++
 				}
 				openQuote = tok.Value
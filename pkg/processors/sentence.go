@@ -0,0 +1,143 @@
+package processors
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+// SentenceProcessor capitalizes the first word of every sentence. It runs
+// after PunctuationProcessor, so terminal marks are already attached to the
+// words they end.
+type SentenceProcessor struct{}
+
+func (p SentenceProcessor) Process(tokens []tokenizer.Token) []tokenizer.Token {
+	capNext := true // the text itself starts a sentence
+	newlines := 0   // consecutive line breaks seen since the last word
+	// continues is set once quoted speech closes on "!" or "?", after
+	// which a lower-case word goes on with the sentence: "Stop!" he said.
+	continues := false
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+
+		switch tok.Type {
+		case tokenizer.Space:
+			newlines += strings.Count(tok.Value, "\n")
+			if newlines >= 2 {
+				capNext = true // a blank line starts a new paragraph
+				continues = false
+			}
+
+		case tokenizer.Word:
+			// Skip leading line breaks and quote or emphasis characters
+			// that the tokenizer keeps inside words
+			lead := len(tok.Value) - len(strings.TrimLeft(tok.Value, " \t\r\n\"“”‘’*_¿¡"))
+			if newlines+strings.Count(tok.Value[:lead], "\n") >= 2 {
+				capNext = true
+				continues = false
+			}
+			if lead == len(tok.Value) {
+				// A stray quote or line break carries no sentence start
+				if closesSpeech(tokens, i) {
+					continues = true
+				}
+				continue
+			}
+			first, _ := utf8.DecodeRuneInString(tok.Value[lead:])
+			if capNext && !(continues && unicode.IsLower(first)) {
+				tokens[i].Value = tok.Value[:lead] + capitalizeFirst(tok.Value[lead:])
+			}
+			capNext = false
+			continues = false
+			newlines = 0
+
+		case tokenizer.Protected:
+			// A sentence may start with a URL or code span, which stays as is
+			capNext = false
+			continues = false
+			newlines = 0
+
+		case tokenizer.Punct:
+			newlines = 0
+			if closesSpeech(tokens, i) {
+				continues = true
+				continue
+			}
+			continues = false
+			switch {
+			case endsSentence(tok.Value) && !followsAbbreviation(tokens, i):
+				capNext = true
+			case strings.ContainsAny(tok.Value, ",;:"):
+				capNext = false
+			}
+			// Quotes, brackets and dashes leave the pending state alone, so
+			// '. "Hello' and '"Stop!" Then' both capitalize.
+		}
+	}
+
+	return tokens
+}
+
+// closesSpeech reports whether tokens[i] is a closing quote right after a
+// "!" or "?", as in '"Stop!" he said'. A closing quote stands alone, while
+// an opening one is attached to the word it opens.
+func closesSpeech(tokens []tokenizer.Token, i int) bool {
+	if strings.Trim(tokens[i].Value, "\"'”’") != "" {
+		return false
+	}
+	if i+1 < len(tokens) && tokens[i+1].Type != tokenizer.Space {
+		return false
+	}
+	// PunctuationProcessor may have spaced the quote off the mark
+	j := i - 1
+	for j >= 0 && tokens[j].Type == tokenizer.Space && !strings.Contains(tokens[j].Value, "\n") {
+		j--
+	}
+	if j < 0 || tokens[j].Type != tokenizer.Punct || !endsSentence(tokens[j].Value) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(tokens[j].Value)
+	return strings.ContainsRune("!?！？", last)
+}
+
+// endsSentence reports whether a punctuation token closes a sentence. An
+// ellipsis trails off rather than ending one.
+func endsSentence(value string) bool {
+	if strings.HasPrefix(value, "..") || strings.Contains(value, "…") {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(value)
+	return strings.ContainsRune(".!?。！？", last)
+}
+
+// followsAbbreviation reports whether the period at tokens[i] belongs to an
+// abbreviation such as "Dr." or an initial such as the "g." in "e.g.".
+func followsAbbreviation(tokens []tokenizer.Token, i int) bool {
+	if tokens[i].Value != "." || i == 0 || tokens[i-1].Type != tokenizer.Word {
+		return false
+	}
+	word := strings.TrimSpace(tokens[i-1].Value)
+	if utf8.RuneCountInString(word) == 1 {
+		return unicode.IsLetter([]rune(word)[0])
+	}
+	return tokenizer.IsAbbreviation(word)
+}
+
+// capitalizeFirst upper-cases the first letter of a plain word. Words that
+// start with a digit or symbol, or already use mixed case such as "iPhone",
+// are left alone.
+func capitalizeFirst(word string) string {
+	first, size := utf8.DecodeRuneInString(word)
+	if !unicode.IsLower(first) {
+		return word
+	}
+	for _, r := range word[size:] {
+		if unicode.IsUpper(r) {
+			return word
+		}
+	}
+	return string(unicode.ToUpper(first)) + word[size:]
+}
@@ -0,0 +1,139 @@
+package processors
+
+import (
+	"testing"
+
+	"go-reloaded/pkg/tokenizer"
+)
+
+func TestSentenceProcessor_Process(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []tokenizer.Token
+		want  string
+	}{
+		{
+			name: "after full stop",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "done"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "he"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "said"},
+			},
+			want: "Done. He said",
+		},
+		{
+			name: "ellipsis does not end a sentence",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "Wait"},
+				{Type: tokenizer.Punct, Value: "..."},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "what"},
+				{Type: tokenizer.Punct, Value: "!?"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "no"},
+			},
+			want: "Wait... what!? No",
+		},
+		{
+			name: "abbreviations",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "ask"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "Dr"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "who"},
+				{Type: tokenizer.Punct, Value: ","},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "e"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Word, Value: "g"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "now"},
+			},
+			want: "Ask Dr. who, e.g. now",
+		},
+		{
+			name: "opening quote",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "end"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: "'"},
+				{Type: tokenizer.Word, Value: "hello"},
+				{Type: tokenizer.Punct, Value: "'"},
+			},
+			want: "End. 'Hello'",
+		},
+		{
+			name: "speech closed by ! or ? goes on",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "\"Stop"},
+				{Type: tokenizer.Punct, Value: "!"},
+				{Type: tokenizer.Word, Value: "\""},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "he"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "said"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Punct, Value: "'"},
+				{Type: tokenizer.Word, Value: "why"},
+				{Type: tokenizer.Punct, Value: "?"},
+				{Type: tokenizer.Punct, Value: "'"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "she"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "asked"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "\"Run"},
+				{Type: tokenizer.Punct, Value: "!"},
+				{Type: tokenizer.Word, Value: "\""},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "Then"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "silence"},
+			},
+			want: "\"Stop!\" he said. 'Why?' she asked. \"Run!\" Then silence",
+		},
+		{
+			name: "new line after sentence and blank line",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "one"},
+				{Type: tokenizer.Punct, Value: "."},
+				{Type: tokenizer.Word, Value: "\ntwo"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "lines"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "\n\nfour"},
+			},
+			want: "One.\nTwo lines \n\nFour",
+		},
+		{
+			name: "mixed case words untouched",
+			input: []tokenizer.Token{
+				{Type: tokenizer.Word, Value: "iPhone"},
+				{Type: tokenizer.Space, Value: " "},
+				{Type: tokenizer.Word, Value: "sales"},
+			},
+			want: "iPhone sales",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			for _, tok := range (SentenceProcessor{}).Process(tt.input) {
+				got += tok.Value
+			}
+			if got != tt.want {
+				t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
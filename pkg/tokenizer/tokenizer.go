@@ -1,6 +1,7 @@
 package tokenizer
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -14,9 +15,72 @@ const (
 	Marker
 	Punct
 	Space
+	// Protected tokens (URLs, emails, code spans, file paths, message
+	// placeholders) are copied through unchanged by every processor.
+	Protected
 )
 
+// typeNames are the display names of the token types, by type.
+var typeNames = []string{"Word", "Marker", "Punct", "Space", "Protected"}
+
+// TypeName returns the name of a token type, e.g. "Word" or "Punct".
+func TypeName(typ int) string {
+	if typ >= 0 && typ < len(typeNames) {
+		return typeNames[typ]
+	}
+	return fmt.Sprintf("Type(%d)", typ)
+}
+
+// ParseType returns the token type named name, as TypeName writes it.
+func ParseType(name string) (int, bool) {
+	for typ, n := range typeNames {
+		if n == name {
+			return typ, true
+		}
+	}
+	return 0, false
+}
+
+// groupPunct lists the marks that combine into a single Punct token when
+// adjacent (e.g. "..." or "!?"), including the full-width forms used in CJK text.
+const groupPunct = ".,!?;:，。！？：；、"
+
+// pairedPunct lists the marks that always form a token of their own.
+const pairedPunct = "«»)[]{}—–"
+
+// SoftBreak is the Unicode line separator. Document handlers join the
+// wrapped lines of a paragraph with it; unlike a newline, it is a space to
+// the tokenizer, so the words on either side of a wrap stay apart.
+const SoftBreak = '\u2028'
+
+// Markup is the object replacement character. Document handlers put it
+// in place of inline markup, such as a tag or a link destination, so that
+// the prose around it is formatted as one run. The tokenizer makes it a
+// space of its own, which stages keep like a line break.
+const Markup = '\uFFFC'
+
+// isSpace reports whether ch is a horizontal space, including the
+// non-breaking and narrow spaces used by French typography, a SoftBreak or
+// Markup.
+func isSpace(ch rune) bool {
+	return ch == ' ' || ch == '\u00A0' || ch == '\u202F' || ch == '\u2009' || ch == SoftBreak || ch == Markup
+}
+
+// Tokenizer splits text into tokens. Its zero value recognizes only the
+// built-in markers.
+type Tokenizer struct {
+	// Markers holds the names of extra markers, in lower case: "sig"
+	// makes "(sig)" and "(sig, 2)" markers.
+	Markers map[string]bool
+}
+
+// Tokenize splits s into tokens, recognizing only the built-in markers.
 func Tokenize(s string) []Token {
+	return Tokenizer{}.Tokenize(s)
+}
+
+// Tokenize splits s into tokens.
+func (t Tokenizer) Tokenize(s string) []Token {
 	runes := []rune(s)
 	var tokens []Token
 	var current strings.Builder
@@ -38,6 +102,36 @@ func Tokenize(s string) []Token {
 	for i := 0; i < len(runes); i++ {
 		ch := runes[i]
 
+		// Inline code spans are protected whole, spaces included
+		if ch == '`' {
+			if n := matchCodeSpan(runes[i:]); n > 0 {
+				flush(detectType(current.String()))
+				tokens = append(tokens, Token{Type: Protected, Value: string(runes[i : i+n])})
+				i += n - 1
+				continue
+			}
+		}
+
+		// Message placeholders such as %s or {name} are protected whole
+		if ch == '%' || ch == '{' {
+			if n := matchPlaceholder(runes[i:]); n > 0 {
+				flush(detectType(current.String()))
+				tokens = append(tokens, Token{Type: Protected, Value: string(runes[i : i+n])})
+				i += n - 1
+				continue
+			}
+		}
+
+		// Numbers, abbreviations, URLs, emails and paths keep their inner
+		// punctuation
+		if current.Len() == 0 {
+			if n, typ := matchWord(runes[i:]); n > 0 {
+				tokens = append(tokens, Token{Type: typ, Value: string(runes[i : i+n])})
+				i += n - 1
+				continue
+			}
+		}
+
 		// Handle punctuation
 		// Handle punctuation groups (e.g., "..." or "!?")
 		// Handle single quotes - check if it's a contraction or standalone quote
@@ -62,12 +156,12 @@ func Tokenize(s string) []Token {
 		}
 
 		// Handle other punctuation with grouping
-		if strings.ContainsRune(".,!?;:", ch) {
+		if strings.ContainsRune(groupPunct, ch) {
 			flush(detectType(current.String()))
 
 			start := i
 			// Combine consecutive punctuation characters
-			for i+1 < len(runes) && strings.ContainsRune(".,!?;:", runes[i+1]) {
+			for i+1 < len(runes) && strings.ContainsRune(groupPunct, runes[i+1]) {
 				i++
 			}
 
@@ -76,28 +170,48 @@ func Tokenize(s string) []Token {
 			continue
 		}
 
-		// Handle spaces
-		if ch == ' ' {
+		// Brackets, guillemets and dashes are never grouped with other marks
+		if strings.ContainsRune(pairedPunct, ch) {
 			flush(detectType(current.String()))
-			tokens = append(tokens, Token{Type: Space, Value: " "})
+			tokens = append(tokens, Token{Type: Punct, Value: string(ch)})
 			continue
 		}
 
-		// Handle markers like (up), (low,2), (hex)
-		if ch == '(' {
+		// A run of two or more hyphens is a dash; a single one joins a word
+		if ch == '-' && i+1 < len(runes) && runes[i+1] == '-' {
 			flush(detectType(current.String()))
 			start := i
-			for i < len(runes) && runes[i] != ')' {
+			for i+1 < len(runes) && runes[i+1] == '-' {
 				i++
 			}
-			if i < len(runes) {
-				marker := string(runes[start : i+1])
-				if isMarker(marker) {
+			tokens = append(tokens, Token{Type: Punct, Value: string(runes[start : i+1])})
+			continue
+		}
+
+		// Handle spaces
+		if isSpace(ch) {
+			flush(detectType(current.String()))
+			tokens = append(tokens, Token{Type: Space, Value: string(ch)})
+			continue
+		}
+
+		// Handle markers like (up), (low,2), (hex); any other parenthesis
+		// opens a bracketed phrase
+		if ch == '(' {
+			flush(detectType(current.String()))
+			end := i
+			for end < len(runes) && runes[end] != ')' {
+				end++
+			}
+			if end < len(runes) {
+				marker := string(runes[i : end+1])
+				if t.isMarker(marker) {
 					tokens = append(tokens, Token{Type: Marker, Value: marker})
+					i = end
 					continue
 				}
 			}
-			tokens = append(tokens, Token{Type: Word, Value: string(runes[start : i+1])})
+			tokens = append(tokens, Token{Type: Punct, Value: "("})
 			continue
 		}
 
@@ -110,11 +224,23 @@ func Tokenize(s string) []Token {
 	return tokens
 }
 
-func isMarker(s string) bool {
+func (t Tokenizer) isMarker(s string) bool {
 	s = strings.ToLower(strings.TrimSpace(s))
-	return strings.HasPrefix(s, "(up") ||
+	if strings.HasPrefix(s, "(up") ||
 		strings.HasPrefix(s, "(low") ||
 		strings.HasPrefix(s, "(cap") ||
 		strings.HasPrefix(s, "(hex") ||
-		strings.HasPrefix(s, "(bin")
+		strings.HasPrefix(s, "(bin") {
+		return true
+	}
+	name, _ := MarkerName(s)
+	return t.Markers[name]
+}
+
+// MarkerName returns the name of a marker in lower case and the text
+// after its comma, both trimmed: "(Sig, 2)" gives "sig" and "2".
+func MarkerName(marker string) (name, arg string) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(marker), "("), ")")
+	name, arg, _ = strings.Cut(inner, ",")
+	return strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(arg)
 }
@@ -2,6 +2,7 @@ package tokenizer
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -68,3 +69,164 @@ func TestTokenize_MarkersWithNumbers(t *testing.T) {
 		t.Errorf("Tokenize() = %#v, want %#v", got, want)
 	}
 }
+
+func TestTokenize_LocaleSpacesAndMarks(t *testing.T) {
+	input := "Oui\u202F! «\u00A0non\u00A0» 好，"
+	want := []Token{
+		{Word, "Oui"},
+		{Space, "\u202F"},
+		{Punct, "!"},
+		{Space, " "},
+		{Punct, "«"},
+		{Space, "\u00A0"},
+		{Word, "non"},
+		{Space, "\u00A0"},
+		{Punct, "»"},
+		{Space, " "},
+		{Word, "好"},
+		{Punct, "，"},
+	}
+	got := Tokenize(input)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %#v, want %#v", got, want)
+	}
+}
+
+func TestTokenize_BracketsAndDashes(t *testing.T) {
+	input := "a (well-known) [fact]--really"
+	want := []Token{
+		{Word, "a"},
+		{Space, " "},
+		{Punct, "("},
+		{Word, "well-known"},
+		{Punct, ")"},
+		{Space, " "},
+		{Punct, "["},
+		{Word, "fact"},
+		{Punct, "]"},
+		{Punct, "--"},
+		{Word, "really"},
+	}
+	got := Tokenize(input)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %#v, want %#v", got, want)
+	}
+}
+
+func TestTokenize_ProtectedWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Token
+	}{
+		{"3.14,", []Token{{Word, "3.14"}, {Punct, ","}}},
+		{"1,000 at 12:30", []Token{{Word, "1,000"}, {Space, " "}, {Word, "at"}, {Space, " "}, {Word, "12:30"}}},
+		{"-1,234,567.89", []Token{{Word, "-1,234,567.89"}}},
+		{"1,2 or 3,4,5", []Token{{Word, "1"}, {Punct, ","}, {Word, "2"}, {Space, " "}, {Word, "or"}, {Space, " "},
+			{Word, "3"}, {Punct, ","}, {Word, "4"}, {Punct, ","}, {Word, "5"}}},
+		{"https://example.com/" + strings.Repeat("a", 300) + ".", []Token{{Protected, "https://example.com/" + strings.Repeat("a", 300)}, {Punct, "."}}},
+		{"e.g. Dr. No", []Token{{Word, "e.g."}, {Space, " "}, {Word, "Dr."}, {Space, " "}, {Word, "No"}}},
+		{"www.example.com.", []Token{{Protected, "www.example.com"}, {Punct, "."}}},
+		{"https://go.dev/doc, ok", []Token{{Protected, "https://go.dev/doc"}, {Punct, ","}, {Space, " "}, {Word, "ok"}}},
+		{"bob@example.org!", []Token{{Protected, "bob@example.org"}, {Punct, "!"}}},
+		{"end.Next", []Token{{Word, "end"}, {Punct, "."}, {Word, "Next"}}},
+	}
+
+	for _, tt := range tests {
+		got := Tokenize(tt.input)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize_CodeSpansAndPaths(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Token
+	}{
+		{"run `go test ./...` now", []Token{{Word, "run"}, {Space, " "}, {Protected, "`go test ./...`"}, {Space, " "}, {Word, "now"}}},
+		{"a ``x ` y`` b", []Token{{Word, "a"}, {Space, " "}, {Protected, "``x ` y``"}, {Space, " "}, {Word, "b"}}},
+		{"an `open span", []Token{{Word, "an"}, {Space, " "}, {Word, "`open"}, {Space, " "}, {Word, "span"}}},
+		{"see /usr/local/bin.", []Token{{Word, "see"}, {Space, " "}, {Protected, "/usr/local/bin"}, {Punct, "."}}},
+		{"./main.go ~/notes", []Token{{Protected, "./main.go"}, {Space, " "}, {Protected, "~/notes"}}},
+		{`C:\Users\me`, []Token{{Protected, `C:\Users\me`}}},
+		{"docs/USAGE.md, and/or", []Token{{Protected, "docs/USAGE.md"}, {Punct, ","}, {Space, " "}, {Word, "and/or"}}},
+	}
+
+	for _, tt := range tests {
+		got := Tokenize(tt.input)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize_Placeholders(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Token
+	}{
+		{"%s files", []Token{{Protected, "%s"}, {Space, " "}, {Word, "files"}}},
+		{"got %1$s,%.2f%%", []Token{{Word, "got"}, {Space, " "}, {Protected, "%1$s"}, {Punct, ","}, {Protected, "%.2f"}, {Protected, "%%"}}},
+		{"hi {name}!", []Token{{Word, "hi"}, {Space, " "}, {Protected, "{name}"}, {Punct, "!"}}},
+		{"{{ count }} items", []Token{{Protected, "{{ count }}"}, {Space, " "}, {Word, "items"}}},
+		{"50% of {a b}", []Token{{Word, "50%"}, {Space, " "}, {Word, "of"}, {Space, " "}, {Punct, "{"}, {Word, "a"}, {Space, " "}, {Word, "b"}, {Punct, "}"}}},
+	}
+
+	for _, tt := range tests {
+		got := Tokenize(tt.input)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := map[int]string{
+		Word:      "Word",
+		Marker:    "Marker",
+		Punct:     "Punct",
+		Space:     "Space",
+		Protected: "Protected",
+		42:        "Type(42)",
+	}
+	for typ, want := range tests {
+		if got := TypeName(typ); got != want {
+			t.Errorf("TypeName(%d) = %q, want %q", typ, got, want)
+		}
+	}
+}
+
+func TestTokenizer_CustomMarkers(t *testing.T) {
+	tok := Tokenizer{Markers: map[string]bool{"sig": true, "tm": true}}
+	input := "Acme(TM) (sig, 2) (nope)"
+	want := []Token{
+		{Word, "Acme"}, {Marker, "(TM)"}, {Space, " "}, {Marker, "(sig, 2)"}, {Space, " "},
+		{Punct, "("}, {Word, "nope"}, {Punct, ")"},
+	}
+	if got := tok.Tokenize(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %#v, want %#v", got, want)
+	}
+	if got := Tokenize("(sig)"); got[0].Type == Marker {
+		t.Errorf("Tokenize() without custom markers = %#v", got)
+	}
+
+	if name, arg := MarkerName(" (Sig , 2) "); name != "sig" || arg != "2" {
+		t.Errorf("MarkerName() = %q, %q", name, arg)
+	}
+}
+
+// Text without spaces must tokenize in linear time, like spaced text.
+func BenchmarkTokenize_Unspaced(b *testing.B) {
+	for _, input := range []struct{ name, text string }{
+		{"spaced", strings.Repeat("ab, ", 10000)},
+		{"unspaced", strings.Repeat("ab,", 10000)},
+	} {
+		b.Run(input.name, func(b *testing.B) {
+			b.SetBytes(int64(len(input.text)))
+			for i := 0; i < b.N; i++ {
+				Tokenize(input.text)
+			}
+		})
+	}
+}
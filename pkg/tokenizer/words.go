@@ -0,0 +1,152 @@
+package tokenizer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordPattern matches a word whose inner punctuation must not be split off.
+type wordPattern struct {
+	re *regexp.Regexp
+	// typ is the token type emitted for a match
+	typ int
+	// trim gives back trailing punctuation, since a sentence may end right
+	// after a link or path
+	trim bool
+}
+
+// wordPatterns are tried in order at the start of a word.
+var wordPatterns = []wordPattern{
+	// URLs with a scheme, or starting with www.
+	{regexp.MustCompile(`^(?:[A-Za-z][A-Za-z0-9+.\-]*://|www\.)\S+`), Protected, true},
+	// Email addresses
+	{regexp.MustCompile(`^[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`), Protected, true},
+	// Bare domains on common top-level domains, with an optional path
+	{regexp.MustCompile(`^[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.(?:com|org|net|edu|gov|io|dev|app|info|co|uk|de|fr)(?:/\S*)?`), Protected, true},
+	// Absolute, home-relative and dot-relative paths: /usr/bin, ~/notes, ./main.go
+	{regexp.MustCompile(`^(?:~|\.{1,2})?/[\w.\-~/]*[\w\-~/]`), Protected, true},
+	// Windows paths: C:\Users\me
+	{regexp.MustCompile(`^[A-Za-z]:\\\S+`), Protected, true},
+	// Relative paths ending in a file name: docs/USAGE.md
+	{regexp.MustCompile(`^[\w.\-]+(?:/[\w.\-]+)*/[\w\-]+\.[A-Za-z0-9]+`), Protected, true},
+	// Thousands separators, decimals, times and versions: 1,000, 3.14,
+	// 12:30, v1.2.3. A comma only counts before a group of three digits, so
+	// lists such as 1,2 are still spaced
+	{regexp.MustCompile(`^[+\-vV]?(?:\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:[.:]\d+)+)`), Word, false},
+	// Dotted abbreviations: e.g., i.e., U.S.A.
+	{regexp.MustCompile(`^(?:[A-Za-z]\.){2,}`), Word, false},
+	// Titles and other abbreviations that take a single period
+	{regexp.MustCompile(`^(?i:mrs|mr|ms|dr|prof|st|jr|sr|vs|cf|fig|approx|dept|est)\.`), Word, false},
+}
+
+// abbreviations lists the words that take a period without ending a sentence.
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true,
+	"st": true, "jr": true, "sr": true, "vs": true, "cf": true,
+	"fig": true, "approx": true, "dept": true, "est": true,
+}
+
+// IsAbbreviation reports whether word, without its period, is an
+// abbreviation such as "Dr" or "vs".
+func IsAbbreviation(word string) bool {
+	return abbreviations[strings.ToLower(word)]
+}
+
+// trailingPunct is given back by trimming patterns.
+const trailingPunct = ".,;:!?'\")]}>"
+
+// maxWordLen caps the runes matchWord looks at. It is called at every word
+// start, so looking as far as the next space would make text without
+// spaces quadratic to tokenize.
+const maxWordLen = 256
+
+// endsWord reports whether r ends the run matchWord looks at.
+func endsWord(r rune) bool {
+	return unicode.IsSpace(r) || r == Markup
+}
+
+// matchWord returns the length in runes and the token type of the protected
+// word starting at runes[0], or 0 if none matches.
+func matchWord(runes []rune) (int, int) {
+	end := 0
+	for end < len(runes) && end < maxWordLen && !endsWord(runes[end]) {
+		end++
+	}
+	truncated := end == maxWordLen && end < len(runes) && !endsWord(runes[end])
+	run := string(runes[:end])
+	if !strings.ContainsAny(run, ".,:@/\\") {
+		return 0, Word
+	}
+
+	for _, p := range wordPatterns {
+		loc := p.re.FindStringIndex(run)
+		if loc == nil {
+			continue
+		}
+		match := run[:loc[1]]
+		if truncated && p.typ == Protected && len(match) == len(run) {
+			// A long URL or path runs past the cap; it takes the rest of the
+			// run, which is only scanned this once
+			for end < len(runes) && !endsWord(runes[end]) {
+				end++
+			}
+			run = string(runes[:end])
+			match = run
+		}
+		if p.trim {
+			match = strings.TrimRight(match, trailingPunct)
+		}
+		// The match must end on a word boundary
+		if next, _ := utf8.DecodeRuneInString(run[len(match):]); len(match) < len(run) &&
+			(unicode.IsLetter(next) || unicode.IsDigit(next)) {
+			continue
+		}
+		if match == "" {
+			continue
+		}
+		return utf8.RuneCountInString(match), p.typ
+	}
+	return 0, Word
+}
+
+// placeholderPattern matches the interpolation placeholders of message
+// catalogs: printf verbs (%s, %1$s, %.2f, %(name)s), and {name} or {{count}}.
+var placeholderPattern = regexp.MustCompile(`^(?:%(?:\d+\$|\([\w.]+\))?[-+#0]*(?:\d+|\*)?(?:\.(?:\d+|\*))?[vTtbcdoOqxXUeEfFgGspw%]|\{\{\s*[\w.]+\s*\}\}|\{[\w.]+\})`)
+
+// matchPlaceholder returns the length in runes of the placeholder starting
+// at runes[0], or 0 if there is none.
+func matchPlaceholder(runes []rune) int {
+	end := len(runes)
+	if end > 64 {
+		end = 64 // placeholders are short; don't convert the whole text
+	}
+	match := placeholderPattern.FindString(string(runes[:end]))
+	return utf8.RuneCountInString(match)
+}
+
+// matchCodeSpan returns the length in runes of the `inline code` span
+// starting at runes[0], closed by a backtick run of the same length, or 0
+// if the span is not closed.
+func matchCodeSpan(runes []rune) int {
+	open := 0
+	for open < len(runes) && runes[open] == '`' {
+		open++
+	}
+	for i := open; i < len(runes); {
+		if runes[i] != '`' {
+			i++
+			continue
+		}
+		n := 0
+		for i+n < len(runes) && runes[i+n] == '`' {
+			n++
+		}
+		if n == open {
+			return i + n
+		}
+		i += n
+	}
+	return 0
+}
@@ -1,6 +1,7 @@
 package golden
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,7 +15,8 @@ import (
 func formatText(input string) string {
 	tokens := tokenizer.Tokenize(input)
 	pl := pipeline.New()
-	processed := pl.Process(tokens)
+	// The built-in stages only fail on cancellation
+	processed, _ := pl.Process(context.Background(), tokens)
 
 	var result string
 	for _, token := range processed {
@@ -1,6 +1,7 @@
 package testutils
 
 import (
+	"context"
 	"testing"
 
 	"go-reloaded/internal/pipeline"
@@ -11,7 +12,8 @@ import (
 func FormatText(input string) string {
 	tokens := tokenizer.Tokenize(input)
 	pl := pipeline.New()
-	processed := pl.Process(tokens)
+	// The built-in stages only fail on cancellation
+	processed, _ := pl.Process(context.Background(), tokens)
 
 	var result string
 	for _, token := range processed {